@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"log"
+	"os/signal"
+	"syscall"
 	"time"
 
 	broker "github.com/LeandroAlcantara-1997/appointment/internal/api"
@@ -16,23 +18,19 @@ func main() {
 
 	ctx = context.WithValue(ctx, types.ContextKey(types.Version), config.NewVersion())
 	ctx = context.WithValue(ctx, types.ContextKey(types.StartedAt), time.Now())
-	_, dep, err := container.New(ctx)
+	ctx, dep, err := container.New(ctx)
 	if err != nil {
 		log.Fatal(err) // log might not be started and because of that dep might not exist
 	}
 
-	conn := dep.Components.RabbitMQ
-	defer conn.Close()
+	// Stop consuming on SIGINT/SIGTERM, the broker finishes the in-flight
+	// deliveries before returning.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	ch, err := conn.Channel()
-	if err != nil {
-		panic(err)
+	if err := broker.Broker(ctx, dep); err != nil {
+		log.Fatal(err)
 	}
-	defer ch.Close()
-
-	// Make a channel to receive messages into infinite loop.
 
-	if err := broker.Broker(ch, dep); err != nil {
-		log.Println(err)
-	}
+	dep.Components.Tracer.Close()
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/internal/api"
+	"github.com/LeandroAlcantara-1997/appointment/internal/config"
+	"github.com/LeandroAlcantara-1997/appointment/internal/container"
+	"github.com/facily-tech/go-core/types"
+)
+
+func main() {
+	ctx := context.Background()
+
+	ctx = context.WithValue(ctx, types.ContextKey(types.Version), config.NewVersion())
+	ctx = context.WithValue(ctx, types.ContextKey(types.StartedAt), time.Now())
+	ctx, dep, err := container.New(ctx)
+	if err != nil {
+		log.Fatal(err) // log might not be started and because of that dep might not exist
+	}
+
+	// Stop relaying on SIGINT/SIGTERM, the entry being published is either
+	// marked sent or relayed again on the next start.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := api.Outbox(ctx, dep); err != nil {
+		log.Fatal(err)
+	}
+
+	dep.Components.Tracer.Close()
+}
@@ -38,14 +38,54 @@ const docTemplate = `{
                     "appointment"
                 ],
                 "summary": "Get all appointments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, up to 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of the page, as returned in next_page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments at or after this date (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments before this date (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "appointment_date",
+                            "-appointment_date"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.AppResponse"
-                            }
+                            "$ref": "#/definitions/model.AppPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -61,11 +101,325 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "post": {
+                "description": "Create a new appointment slot for a salon",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Create an appointment",
+                "parameters": [
+                    {
+                        "description": "Appointment",
+                        "name": "appointment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpsertAppointment"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Appointment overlaps another slot, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Appointment date must be in the future, or the idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
             }
         },
         "/appointment/available": {
             "get": {
-                "description": "get all available appointments",
+                "description": "get the available appointments that have not started yet, ordered by date",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Get available appointments",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Salon IDs, repeated or comma separated",
+                        "name": "salon_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "haircut",
+                                "manicure",
+                                "coloring"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Service types, repeated or comma separated",
+                        "name": "service_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, up to 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of the page, as returned in next_page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments at or after this date (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments before this date (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "appointment_date",
+                            "-appointment_date"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointment/generate": {
+            "post": {
+                "description": "Create the available slots of a salon from its weekly opening hours, skipping the slots that already started or overlap a stored one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Generate appointments",
+                "parameters": [
+                    {
+                        "description": "Opening hours template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SlotTemplate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenerateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointment/professional/{id}": {
+            "get": {
+                "description": "get by professional ID and return an appointment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Get appointments by professional id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Professional ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, up to 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of the page, as returned in next_page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments at or after this date (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments before this date (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "appointment_date",
+                            "-appointment_date"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot read path",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointment/salon/{id}": {
+            "get": {
+                "description": "get by salon ID and return an appointment",
                 "consumes": [
                     "application/json"
                 ],
@@ -75,15 +429,550 @@ const docTemplate = `{
                 "tags": [
                     "appointment"
                 ],
-                "summary": "Get available appointments",
+                "summary": "Get appointments by salon id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Salon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, up to 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of the page, as returned in next_page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments at or after this date (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments before this date (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "appointment_date",
+                            "-appointment_date"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot read path",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointment/user/{id}": {
+            "get": {
+                "description": "Get by user ID and return an appointment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Get appointments by user id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, up to 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of the page, as returned in next_page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments at or after this date (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only appointments before this date (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "appointment_date",
+                            "-appointment_date"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot read path",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointment/{id}": {
+            "get": {
+                "description": "get appointment by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Get appointment by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the appointment, to send as If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot read path or invalid appointment id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Get Appointment by ID and body for update",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Update an appointment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Appointment",
+                        "name": "appointment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the appointment was read with",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated appointment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot read path or invalid appointment id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Appointment overlaps another slot, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Appointment was changed by someone else, read it again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Appointment date must be in the future, or the idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "get string by ID and delete an appointment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Delete appointments by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the appointment was read with",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Cannot read path or invalid appointment id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Appointment was changed by someone else, read it again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change some fields of an appointment with a JSON Merge Patch, null clears a field",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Patch an appointment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the appointment was read with",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated appointment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body or invalid appointment id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Appointment not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Appointment overlaps another slot, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Appointment was changed by someone else, read it again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Appointment date must be in the future, or the idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "An error happened in database",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointment/{id}/book": {
+            "post": {
+                "description": "Book an available appointment by ID for the user in the body",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointment"
+                ],
+                "summary": "Book an appointment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Booking",
+                        "name": "booking",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BookAppointment"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.AppResponse"
-                            }
+                            "$ref": "#/definitions/model.AppResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body or invalid appointment id",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -92,6 +981,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Appointment already booked, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "An error happened in database",
                         "schema": {
@@ -101,9 +1002,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/appointment/salon/{id}": {
-            "get": {
-                "description": "get by salon ID and return an appointment",
+        "/appointment/{id}/check-in": {
+            "post": {
+                "description": "Mark that the user of an appointment arrived at the salon",
                 "consumes": [
                     "application/json"
                 ],
@@ -113,28 +1014,31 @@ const docTemplate = `{
                 "tags": [
                     "appointment"
                 ],
-                "summary": "Get appointments by salon id",
+                "summary": "Check in an appointment",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Salon ID",
+                        "type": "string",
+                        "description": "Appointment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.AppResponse"
-                            }
+                            "$ref": "#/definitions/model.AppResponse"
                         }
                     },
                     "400": {
-                        "description": "Cannot read path",
+                        "description": "Cannot read path or invalid appointment id",
                         "schema": {
                             "type": "string"
                         }
@@ -145,6 +1049,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Invalid appointment status transition, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "An error happened in database",
                         "schema": {
@@ -154,9 +1070,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/appointment/user/{id}": {
-            "get": {
-                "description": "Get by user ID and return an appointment",
+        "/appointment/{id}/complete": {
+            "post": {
+                "description": "Mark a checked-in appointment as completed",
                 "consumes": [
                     "application/json"
                 ],
@@ -166,28 +1082,31 @@ const docTemplate = `{
                 "tags": [
                     "appointment"
                 ],
-                "summary": "Get appointments by user id",
+                "summary": "Complete an appointment",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
+                        "type": "string",
+                        "description": "Appointment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.AppResponse"
-                            }
+                            "$ref": "#/definitions/model.AppResponse"
                         }
                     },
                     "400": {
-                        "description": "Cannot read path",
+                        "description": "Cannot read path or invalid appointment id",
                         "schema": {
                             "type": "string"
                         }
@@ -198,6 +1117,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Invalid appointment status transition, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "An error happened in database",
                         "schema": {
@@ -207,9 +1138,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/appointment/{id}": {
-            "put": {
-                "description": "Get Appointment by ID and body for update",
+        "/appointment/{id}/confirm": {
+            "post": {
+                "description": "Confirm a booked appointment by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -219,7 +1150,7 @@ const docTemplate = `{
                 "tags": [
                     "appointment"
                 ],
-                "summary": "Update an appointment",
+                "summary": "Confirm an appointment",
                 "parameters": [
                     {
                         "type": "string",
@@ -229,13 +1160,10 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Appointment",
-                        "name": "appointment",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -246,7 +1174,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Cannot read path",
+                        "description": "Cannot read path or invalid appointment id",
                         "schema": {
                             "type": "string"
                         }
@@ -257,6 +1185,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Invalid appointment status transition, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "An error happened in database",
                         "schema": {
@@ -264,9 +1204,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "get string by ID and delete an appointment",
+            }
+        },
+        "/appointment/{id}/no-show": {
+            "post": {
+                "description": "Mark that the user of a booked appointment did not show up",
                 "consumes": [
                     "application/json"
                 ],
@@ -276,7 +1218,7 @@ const docTemplate = `{
                 "tags": [
                     "appointment"
                 ],
-                "summary": "Delete appointments by id",
+                "summary": "Mark an appointment as no-show",
                 "parameters": [
                     {
                         "type": "string",
@@ -284,14 +1226,23 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AppResponse"
+                        }
                     },
                     "400": {
-                        "description": "Cannot read path",
+                        "description": "Cannot read path or invalid appointment id",
                         "schema": {
                             "type": "string"
                         }
@@ -302,6 +1253,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Invalid appointment status transition, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "An error happened in database",
                         "schema": {
@@ -338,6 +1301,19 @@ const docTemplate = `{
                         "name": "user",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Applies the request once per key, a request sent again gets the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the appointment was read with",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -345,7 +1321,13 @@ const docTemplate = `{
                         "description": ""
                     },
                     "400": {
-                        "description": "Cannot read path",
+                        "description": "Cannot read path or invalid appointment id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Appointment belongs to another user",
                         "schema": {
                             "type": "string"
                         }
@@ -356,6 +1338,30 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Invalid appointment status transition, or a request with this idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Appointment was changed by someone else, read it again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key was used by another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "An error happened in database",
                         "schema": {
@@ -367,6 +1373,25 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "model.AppPageResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AppResponse"
+                    }
+                },
+                "next_page": {
+                    "type": "string",
+                    "example": "eyJkIjoiMjAyMi0wNi0yM1QyMToxMjowMloiLCJpZCI6IjYyYjY1MzAwZTFkN2VhYjFlYTlhNjgxZCJ9"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
         "model.AppResponse": {
             "type": "object",
             "properties": {
@@ -374,17 +1399,235 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2022-06-23T21:12:02.000000001Z"
                 },
+                "duration_minutes": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "end_date": {
+                    "type": "string",
+                    "example": "2022-06-23T21:42:02.000000001Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "62b65300e1d7eab1ea9a681d"
+                },
+                "price_cents": {
+                    "type": "integer",
+                    "example": 4500
+                },
+                "professional_id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "salon_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "service_type": {
+                    "type": "string",
+                    "example": "haircut"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "booked"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "model.BookAppointment": {
+            "type": "object",
+            "properties": {
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.Break": {
+            "type": "object",
+            "required": [
+                "end",
+                "start"
+            ],
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "example": "13:00"
+                },
+                "start": {
+                    "type": "string",
+                    "example": "12:00"
+                }
+            }
+        },
+        "model.GenerateResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "model.OpeningHours": {
+            "type": "object",
+            "required": [
+                "close",
+                "open",
+                "weekday"
+            ],
+            "properties": {
+                "breaks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Break"
+                    }
+                },
+                "close": {
+                    "type": "string",
+                    "example": "18:00"
+                },
+                "open": {
+                    "type": "string",
+                    "example": "09:00"
+                },
+                "weekday": {
+                    "type": "string",
+                    "enum": [
+                        "sunday",
+                        "monday",
+                        "tuesday",
+                        "wednesday",
+                        "thursday",
+                        "friday",
+                        "saturday"
+                    ],
+                    "example": "monday"
+                }
+            }
+        },
+        "model.SlotTemplate": {
+            "type": "object",
+            "required": [
+                "days",
+                "from",
+                "salon_id",
+                "slot_minutes",
+                "time_zone",
+                "to"
+            ],
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/model.OpeningHours"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2022-07-01"
+                },
+                "price_cents": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 4500
+                },
+                "professional_id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "salon_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "service_type": {
+                    "type": "string",
+                    "enum": [
+                        "haircut",
+                        "manicure",
+                        "coloring"
+                    ],
+                    "example": "haircut"
+                },
+                "slot_minutes": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 30
+                },
+                "time_zone": {
+                    "type": "string",
+                    "example": "America/Sao_Paulo"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2022-07-31"
+                }
+            }
+        },
+        "model.UpsertAppointment": {
+            "type": "object",
+            "required": [
+                "appointment_date",
+                "salon_id"
+            ],
+            "properties": {
+                "appointment_date": {
+                    "type": "string",
+                    "example": "2022-06-23T21:12:02.000000001Z"
+                },
+                "duration_minutes": {
+                    "description": "DurationMinutes defaults to SlotDuration when it is not given.",
+                    "type": "integer",
+                    "maximum": 1440,
+                    "minimum": 1,
+                    "example": 45
+                },
                 "id": {
                     "type": "string",
                     "example": "62b65300e1d7eab1ea9a681d"
                 },
+                "price_cents": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 4500
+                },
+                "professional_id": {
+                    "type": "integer",
+                    "example": 3
+                },
                 "salon_id": {
                     "type": "integer",
                     "example": 1
                 },
+                "service_type": {
+                    "type": "string",
+                    "enum": [
+                        "haircut",
+                        "manicure",
+                        "coloring"
+                    ],
+                    "example": "haircut"
+                },
                 "user_id": {
                     "type": "integer",
                     "example": 1
+                },
+                "version": {
+                    "description": "Version is the version the update applies to, it is ignored on create.",
+                    "type": "integer",
+                    "example": 3
                 }
             }
         }
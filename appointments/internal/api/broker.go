@@ -1,15 +1,65 @@
 package api
 
 import (
+	"context"
+
 	"github.com/LeandroAlcantara-1997/appointment/internal/container"
+	rabbitConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/rabbitmq"
 	transport "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/transport"
+	"github.com/pkg/errors"
 	"github.com/streadway/amqp"
 )
 
-func Broker(ch *amqp.Channel, dep *container.Dependency) error {
-	if err := transport.NewBroker(dep.Services.Appointments, ch); err != nil {
-		return err
+// Broker consumes the appointment queues until ctx is done, then closes the
+// connection once the in-flight deliveries are handled. It subscribes again
+// whenever the connection to the broker is lost, see reconnect.
+func Broker(ctx context.Context, dep *container.Dependency) error {
+	return reconnect(ctx, dep, consume)
+}
+
+// consume subscribes to the queues on a new channel of conn and blocks until
+// ctx is done or either the channel or the connection is closed.
+func consume(ctx context.Context, conn *amqp.Connection, dep *container.Dependency) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return errors.Wrap(err, "open broker channel")
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(dep.Rabbit.Prefetch, 0, false); err != nil {
+		return errors.Wrap(err, "set broker prefetch")
+	}
+
+	topology := newTopology(dep.Rabbit)
+	if err := transport.DeclareTopology(ch, topology); err != nil {
+		return errors.Wrap(err, "declare broker topology")
 	}
 
-	return nil
+	ctx, cancel := watch(ctx, conn, ch)
+	defer cancel()
+
+	return transport.NewBroker(ctx, dep.Services.Appointments, dep.Idempotency, ch, topology)
+}
+
+func newTopology(cfg rabbitConfig.Config) transport.Topology {
+	retry := transport.DefaultRetryPolicy
+	retry.DeadLetterExchange = cfg.DeadLetterExchange
+
+	return transport.Topology{
+		Exchange:                cfg.Exchange,
+		CreateQueue:             cfg.CreateQueue,
+		MakeQueue:               cfg.MakeQueue,
+		GenerateQueue:           cfg.GenerateQueue,
+		UpdateQueue:             cfg.UpdateQueue,
+		CancelQueue:             cfg.CancelQueue,
+		DeleteQueue:             cfg.DeleteQueue,
+		FindQueue:               cfg.FindQueue,
+		FindAllQueue:            cfg.FindAllQueue,
+		FindByUserQueue:         cfg.FindByUserQueue,
+		FindBySalonQueue:        cfg.FindBySalonQueue,
+		FindByProfessionalQueue: cfg.FindByProfessionalQueue,
+		AvailableQueue:          cfg.AvailableQueue,
+		ConsumerTag:             cfg.ConsumerTag,
+		Retry:                   retry,
+	}
 }
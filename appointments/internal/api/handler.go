@@ -29,7 +29,7 @@ func Handler(ctx context.Context, dep *container.Dependency) http.Handler {
 		httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
 	))
 
-	appointmentHandler := appTransport.NewHTTPHandler(dep.Services.Appointments)
+	appointmentHandler := appTransport.NewHTTPHandler(dep.Services.Appointments, dep.Idempotency)
 	r.Mount("/v1/appointment", appointmentHandler)
 
 	return r
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+
+	"github.com/LeandroAlcantara-1997/appointment/internal/container"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/event"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// Outbox relays the outbox to the events exchange until ctx is done. It
+// resumes whenever the connection to the broker is lost, see reconnect.
+func Outbox(ctx context.Context, dep *container.Dependency) error {
+	return reconnect(ctx, dep, relay)
+}
+
+// relay publishes the outbox on a new channel of conn, in confirm mode so an
+// entry is only marked sent once the broker has it.
+func relay(ctx context.Context, conn *amqp.Connection, dep *container.Dependency) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return errors.Wrap(err, "open broker channel")
+	}
+	defer ch.Close()
+
+	publisher, err := event.NewConfirmPublisher(ch, dep.Rabbit.EventsExchange)
+	if err != nil {
+		return errors.Wrap(err, "declare events exchange")
+	}
+
+	ctx, cancel := watch(ctx, conn, ch)
+	defer cancel()
+
+	return event.NewRelay(dep.Outbox, publisher, dep.Rabbit.RelayBatch, dep.Rabbit.RelayInterval,
+		dep.Rabbit.RelayMaxAttempts).Run(ctx)
+}
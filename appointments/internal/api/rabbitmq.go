@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/internal/container"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// ErrNoBroker is returned when the storage backend runs without a broker.
+var ErrNoBroker = errors.New("no broker connection, the memory storage backend only runs the API")
+
+// session uses conn until ctx is done or either the connection or the
+// channels it opened are closed.
+type session func(ctx context.Context, conn *amqp.Connection, dep *container.Dependency) error
+
+// reconnect runs the session until ctx is done, then closes the connection.
+// When the connection to the broker is lost it reconnects with exponential
+// backoff and runs the session again. The first session fails fast, an
+// error there is a configuration error rather than a broker restart.
+func reconnect(ctx context.Context, dep *container.Dependency, run session) error {
+	conn := dep.Components.RabbitMQ
+	if conn == nil {
+		return ErrNoBroker
+	}
+
+	if err := run(ctx, conn, dep); err != nil {
+		conn.Close()
+		return err
+	}
+
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		delay := dep.Rabbit.Backoff(attempt)
+		log.Printf("broker connection lost, reconnecting in %s", delay)
+		select {
+		case <-ctx.Done():
+			continue
+		case <-time.After(delay):
+		}
+
+		if conn.IsClosed() {
+			reconnected, err := amqp.Dial(dep.Rabbit.URL())
+			if err != nil {
+				log.Printf("reconnect to broker: %v", err)
+				continue
+			}
+			conn = reconnected
+		}
+
+		started := time.Now()
+		if err := run(ctx, conn, dep); err != nil {
+			log.Printf("resume broker session: %v", err)
+			continue
+		}
+
+		// The session was healthy, start the next backoff over.
+		if time.Since(started) > dep.Rabbit.ReconnectMax {
+			attempt = -1
+		}
+	}
+
+	if !conn.IsClosed() {
+		return conn.Close()
+	}
+
+	return nil
+}
+
+// watch returns a context cancelled, besides when ctx is done, once conn or
+// ch is closed.
+func watch(ctx context.Context, conn *amqp.Connection, ch *amqp.Channel) (context.Context, context.CancelFunc) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case err := <-connClosed:
+			log.Printf("broker connection closed: %v", err)
+		case err := <-chClosed:
+			log.Printf("broker channel closed: %v", err)
+		case <-ctx.Done():
+			return
+		}
+		cancel()
+	}()
+
+	return ctx, cancel
+}
@@ -2,13 +2,18 @@ package container
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/LeandroAlcantara-1997/appointment/internal/config"
+	cacheConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/cache"
 	mongoConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/mongo"
+	postgresConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/postgres"
 	rabbitConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/rabbitmq"
 	redisConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/redis"
 	splunkConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/splunk"
+	storageConfig "github.com/LeandroAlcantara-1997/appointment/pkg/core/storage"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/event"
 	lg "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/log"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
 	app "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/service"
@@ -18,16 +23,20 @@ import (
 	"github.com/facily-tech/go-core/telemetry"
 	"github.com/facily-tech/go-core/types"
 	"github.com/go-redis/redis"
+	_ "github.com/lib/pq" // registers the postgres driver
 	"github.com/streadway/amqp"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type envs struct {
-	Mongo  mongoConfig.Config
-	Redis  redisConfig.Config
-	Rabbit rabbitConfig.Config
-	Splunk splunkConfig.Config
+	Storage  storageConfig.Config
+	Cache    cacheConfig.Config
+	Mongo    mongoConfig.Config
+	Postgres postgresConfig.Config
+	Redis    redisConfig.Config
+	Rabbit   rabbitConfig.Config
+	Splunk   splunkConfig.Config
 }
 
 // Components are a like service, but it doesn't include business case
@@ -36,6 +45,7 @@ type components struct {
 	Log         log.Logger
 	Tracer      telemetry.Tracer
 	MongoClient *mongo.Client
+	Postgres    *sql.DB
 	RedisClient *redis.Client
 	RabbitMQ    *amqp.Connection
 	Splunk      *splunk.Client
@@ -51,6 +61,12 @@ type Services struct {
 type Dependency struct {
 	Components components
 	Services   Services
+	// Rabbit holds the broker topology settings used by the consumers.
+	Rabbit rabbitConfig.Config
+	// Outbox holds the events waiting to be relayed to the broker.
+	Outbox repository.OutboxI
+	// Idempotency holds the responses of the writes sent with an idempotency key.
+	Idempotency repository.IdempotencyI
 }
 
 func New(ctx context.Context) (context.Context, *Dependency, error) {
@@ -64,21 +80,22 @@ func New(ctx context.Context) (context.Context, *Dependency, error) {
 		return nil, nil, err
 	}
 
-	apService, err := app.NewService(
-		lg.NewSplunkLog(cmp.Splunk,
-			envs.Splunk.Source,
-			envs.Splunk.SourceType,
-			envs.Splunk.Index,
-		),
-		repository.NewMongoRepostory(
-			cmp.MongoClient,
-			envs.Mongo.Database,
-			envs.Mongo.Collection,
-		),
-		repository.NewRedisRepository(
-			cmp.RedisClient,
-		),
-	)
+	var b *backend
+	switch envs.Storage.Backend {
+	case storageConfig.BackendMongo:
+		b, err = mongoBackend(ctx, envs, cmp)
+	case storageConfig.BackendPostgres:
+		b, err = postgresBackend(ctx, envs, cmp)
+	case storageConfig.BackendMemory:
+		b = memoryBackend()
+	default:
+		err = fmt.Errorf("unknown storage backend %q", envs.Storage.Backend)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	apService, err := app.NewService(b.log, b.repository, b.publisher)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -89,18 +106,136 @@ func New(ctx context.Context) (context.Context, *Dependency, error) {
 	}
 
 	dep := Dependency{
-		Components: *cmp,
-		Services:   srv,
+		Components:  *cmp,
+		Services:    srv,
+		Rabbit:      envs.Rabbit,
+		Outbox:      b.outbox,
+		Idempotency: b.idempotency,
 	}
 
 	return ctx, &dep, err
 }
+
+// backend is what the service stores the appointments in.
+type backend struct {
+	log         lg.AppointmentLogI
+	repository  repository.AppointmentRepositoryI
+	publisher   event.Publisher
+	outbox      repository.OutboxI
+	idempotency repository.IdempotencyI
+}
+
+func mongoBackend(ctx context.Context, envs envs, cmp *components) (*backend, error) {
+	mongoRepository := repository.NewMongoRepostory(
+		cmp.MongoClient,
+		envs.Mongo.Database,
+		envs.Mongo.Collection,
+	)
+	if err := mongoRepository.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	outbox := repository.NewMongoOutbox(
+		cmp.MongoClient,
+		envs.Mongo.Database,
+		envs.Mongo.OutboxCollection,
+	)
+	if err := outbox.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	l := lg.NewSplunkLog(cmp.Splunk,
+		envs.Splunk.Source,
+		envs.Splunk.SourceType,
+		envs.Splunk.Index,
+	)
+
+	return &backend{
+		log: l,
+		repository: repository.NewCachedRepository(mongoRepository,
+			repository.NewRedisCache(cmp.RedisClient), cacheTTL(envs.Cache), l),
+		publisher:   event.NewOutboxPublisher(outbox),
+		outbox:      outbox,
+		idempotency: repository.NewRedisIdempotency(cmp.RedisClient),
+	}, nil
+}
+
+func postgresBackend(ctx context.Context, envs envs, cmp *components) (*backend, error) {
+	postgresRepository := repository.NewPostgresRepository(cmp.Postgres)
+	if err := postgresRepository.Migrate(ctx); err != nil {
+		return nil, err
+	}
+
+	outbox := repository.NewPostgresOutbox(cmp.Postgres)
+
+	l := lg.NewSplunkLog(cmp.Splunk,
+		envs.Splunk.Source,
+		envs.Splunk.SourceType,
+		envs.Splunk.Index,
+	)
+
+	return &backend{
+		log: l,
+		repository: repository.NewCachedRepository(postgresRepository,
+			repository.NewRedisCache(cmp.RedisClient), cacheTTL(envs.Cache), l),
+		publisher:   event.NewOutboxPublisher(outbox),
+		outbox:      outbox,
+		idempotency: repository.NewRedisIdempotency(cmp.RedisClient),
+	}, nil
+}
+
+// memoryBackend keeps everything in the process. There is no outbox, so
+// the events are discarded, and no cache, as the appointments are already
+// in memory.
+func memoryBackend() *backend {
+	return &backend{
+		log:         lg.NewStdLog(),
+		repository:  repository.NewInMemoryRepository(),
+		idempotency: repository.NewInMemoryIdempotency(),
+	}
+}
+
+func cacheTTL(c cacheConfig.Config) repository.CacheTTL {
+	return repository.CacheTTL{
+		ByID:           c.ByIDTTL,
+		ByUser:         c.ByUserTTL,
+		BySalon:        c.BySalonTTL,
+		ByProfessional: c.ByProfessionalTTL,
+		NotFound:       c.NotFoundTTL,
+	}
+}
+
+// loadEnvs only loads the settings of the external services when the
+// storage backend uses them.
 func loadEnvs(ctx context.Context) (envs, error) {
-	mongoDB := mongoConfig.Config{}
-	if err := env.LoadEnv(ctx, &mongoDB, mongoConfig.ConfigPrefix); err != nil {
+	storage := storageConfig.Config{}
+	if err := env.LoadEnv(ctx, &storage, storageConfig.ConfigPrefix); err != nil {
 		return envs{}, err
 	}
 
+	cache := cacheConfig.Config{}
+	if err := env.LoadEnv(ctx, &cache, cacheConfig.ConfigPrefix); err != nil {
+		return envs{}, err
+	}
+
+	if storage.Backend != storageConfig.BackendMongo && storage.Backend != storageConfig.BackendPostgres {
+		return envs{Storage: storage, Cache: cache}, nil
+	}
+
+	mongoDB := mongoConfig.Config{}
+	if storage.Backend == storageConfig.BackendMongo {
+		if err := env.LoadEnv(ctx, &mongoDB, mongoConfig.ConfigPrefix); err != nil {
+			return envs{}, err
+		}
+	}
+
+	postgresDB := postgresConfig.Config{}
+	if storage.Backend == storageConfig.BackendPostgres {
+		if err := env.LoadEnv(ctx, &postgresDB, postgresConfig.ConfigPrefix); err != nil {
+			return envs{}, err
+		}
+	}
+
 	redisDB := redisConfig.Config{}
 	if err := env.LoadEnv(ctx, &redisDB, redisConfig.ConfigPrefix); err != nil {
 		return envs{}, err
@@ -115,10 +250,13 @@ func loadEnvs(ctx context.Context) (envs, error) {
 		return envs{}, err
 	}
 	return envs{
-		Mongo:  mongoDB,
-		Redis:  redisDB,
-		Rabbit: rabbit,
-		Splunk: splunk,
+		Storage:  storage,
+		Cache:    cache,
+		Mongo:    mongoDB,
+		Postgres: postgresDB,
+		Redis:    redisDB,
+		Rabbit:   rabbit,
+		Splunk:   splunk,
 	}, nil
 }
 
@@ -153,14 +291,34 @@ func setupComponents(ctx context.Context, envs envs) (*components, error) {
 		return nil, err
 	}
 
-	clientMongo, err := mongo.Connect(context.Background(),
-		options.Client().ApplyURI(fmt.Sprintf("mongodb://%s:%s@%s:27017",
-			envs.Mongo.User,
-			envs.Mongo.Password,
-			envs.Mongo.Host)))
+	cmp := &components{
+		Log:    l,
+		Tracer: tracer,
+	}
+	switch envs.Storage.Backend {
+	case storageConfig.BackendMongo:
+		clientMongo, err := mongo.Connect(context.Background(),
+			options.Client().ApplyURI(fmt.Sprintf("mongodb://%s:%s@%s:27017",
+				envs.Mongo.User,
+				envs.Mongo.Password,
+				envs.Mongo.Host)))
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+		cmp.MongoClient = clientMongo
+	case storageConfig.BackendPostgres:
+		clientPostgres, err := sql.Open("postgres", envs.Postgres.DSN())
+		if err != nil {
+			return nil, err
+		}
+
+		if err := clientPostgres.PingContext(ctx); err != nil {
+			return nil, err
+		}
+		cmp.Postgres = clientPostgres
+	default:
+		return cmp, nil
 	}
 
 	clientRedis := redis.NewClient(&redis.Options{
@@ -168,8 +326,7 @@ func setupComponents(ctx context.Context, envs envs) (*components, error) {
 		Password: envs.Redis.Password,
 	})
 
-	clientRabbitMQ, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@message-broker",
-		envs.Rabbit.User, envs.Rabbit.Password))
+	clientRabbitMQ, err := amqp.Dial(envs.Rabbit.URL())
 	if err != nil {
 		return nil, err
 	}
@@ -185,13 +342,10 @@ func setupComponents(ctx context.Context, envs envs) (*components, error) {
 		envs.Splunk.Index,
 	)
 
-	return &components{
-		Log:         l,
-		Tracer:      tracer,
-		MongoClient: clientMongo,
-		RedisClient: clientRedis,
-		RabbitMQ:    clientRabbitMQ,
-		Splunk:      clientSplunk,
-		// include components initialized bellow here
-	}, nil
+	cmp.RedisClient = clientRedis
+	cmp.RabbitMQ = clientRabbitMQ
+	cmp.Splunk = clientSplunk
+	// include components initialized above here
+
+	return cmp, nil
 }
@@ -0,0 +1,17 @@
+package cache
+
+import "time"
+
+const ConfigPrefix = "CACHE_"
+
+// Config is how long each query of the appointments stays cached, zero
+// disables the cache of the query. It also bounds how long an entry loaded
+// by one process can outlive a write made by another, so keep it short.
+type Config struct {
+	ByIDTTL           time.Duration `env:"BY_ID_TTL, default=1m"`
+	ByUserTTL         time.Duration `env:"BY_USER_TTL, default=1m"`
+	BySalonTTL        time.Duration `env:"BY_SALON_TTL, default=1m"`
+	ByProfessionalTTL time.Duration `env:"BY_PROFESSIONAL_TTL, default=1m"`
+	// NotFoundTTL is how long an appointment id found missing is remembered.
+	NotFoundTTL time.Duration `env:"NOT_FOUND_TTL, default=1m"`
+}
@@ -8,4 +8,6 @@ type Config struct {
 	Password   string `env:"PASSWORD, required"`
 	Database   string `env:"DATABASE, required"`
 	Collection string `env:"COLLECTION, required"`
+	// OutboxCollection holds the events stored with the appointment changes.
+	OutboxCollection string `env:"OUTBOX_COLLECTION, default=outbox"`
 }
@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// ConfigPrefix matches the variables of the postgres image, so
+// env/postgres.env configures both the database and the service.
+const ConfigPrefix = "POSTGRES_"
+
+type Config struct {
+	Host     string `env:"HOST, default=postgres_db"`
+	Port     int    `env:"PORT, default=5432"`
+	User     string `env:"USER, required"`
+	Password string `env:"PASSWORD, required"`
+	Database string `env:"DB, required"`
+	SSLMode  string `env:"SSLMODE, default=disable"`
+}
+
+// DSN returns the connection string of the database.
+func (c Config) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     c.Host + ":" + strconv.Itoa(c.Port),
+		Path:     c.Database,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+
+	return dsn.String()
+}
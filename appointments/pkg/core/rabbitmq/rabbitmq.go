@@ -1,8 +1,81 @@
 package rabbitmq
 
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
 const ConfigPrefix = "RABBIT_"
 
 type Config struct {
 	User     string `env:"USER, required"`
 	Password string `env:"PASSWORD, required"`
+	Host     string `env:"HOST, default=message-broker"`
+	Port     int    `env:"PORT, default=5672"`
+	VHost    string `env:"VHOST, default=/"`
+
+	// Exchange is the direct exchange requests are published to, routed to
+	// the queue of the same name.
+	Exchange                string `env:"EXCHANGE, default=appointments"`
+	DeadLetterExchange      string `env:"DEAD_LETTER_EXCHANGE, default=appointments.dlx"`
+	CreateQueue             string `env:"CREATE_QUEUE, default=create-appointment"`
+	MakeQueue               string `env:"MAKE_QUEUE, default=make-appointment"`
+	GenerateQueue           string `env:"GENERATE_QUEUE, default=generate-appointments"`
+	UpdateQueue             string `env:"UPDATE_QUEUE, default=update-appointment"`
+	CancelQueue             string `env:"CANCEL_QUEUE, default=cancel-appointment"`
+	DeleteQueue             string `env:"DELETE_QUEUE, default=delete-appointment"`
+	FindQueue               string `env:"FIND_QUEUE, default=find-appointment"`
+	FindAllQueue            string `env:"FIND_ALL_QUEUE, default=find-appointments"`
+	FindByUserQueue         string `env:"FIND_BY_USER_QUEUE, default=find-appointments-by-user"`
+	FindBySalonQueue        string `env:"FIND_BY_SALON_QUEUE, default=find-appointments-by-salon"`
+	FindByProfessionalQueue string `env:"FIND_BY_PROFESSIONAL_QUEUE, default=find-appointments-by-professional"`
+	AvailableQueue          string `env:"AVAILABLE_QUEUE, default=find-available-appointments"`
+
+	// EventsExchange is the topic exchange appointment events are published
+	// to, routed by event type.
+	EventsExchange string `env:"EVENTS_EXCHANGE, default=appointments.events"`
+	// RelayBatch is how many outbox entries the relay reads at once, and
+	// RelayInterval how often it looks for new ones. An entry that fails
+	// RelayMaxAttempts times is parked and no longer relayed.
+	RelayBatch       int           `env:"RELAY_BATCH, default=100"`
+	RelayInterval    time.Duration `env:"RELAY_INTERVAL, default=1s"`
+	RelayMaxAttempts int           `env:"RELAY_MAX_ATTEMPTS, default=10"`
+
+	// Prefetch is how many unacked deliveries the broker sends at once.
+	Prefetch    int    `env:"PREFETCH, default=10"`
+	ConsumerTag string `env:"CONSUMER_TAG, default=appointments"`
+
+	// ReconnectMin and ReconnectMax bound the delay between reconnection
+	// attempts when the connection to the broker is lost.
+	ReconnectMin time.Duration `env:"RECONNECT_MIN, default=1s"`
+	ReconnectMax time.Duration `env:"RECONNECT_MAX, default=30s"`
+}
+
+// URL returns the address to dial the broker.
+func (c Config) URL() string {
+	return amqp.URI{
+		Scheme:   "amqp",
+		Host:     c.Host,
+		Port:     c.Port,
+		Username: c.User,
+		Password: c.Password,
+		Vhost:    c.VHost,
+	}.String()
+}
+
+// Backoff returns the delay before the given reconnection attempt, starting
+// at zero. The delay doubles on every attempt from ReconnectMin up to
+// ReconnectMax.
+func (c Config) Backoff(attempt int) time.Duration {
+	delay := c.ReconnectMin
+	for i := 0; i < attempt && delay < c.ReconnectMax; i++ {
+		delay *= 2
+	}
+
+	if delay > c.ReconnectMax {
+		return c.ReconnectMax
+	}
+
+	return delay
 }
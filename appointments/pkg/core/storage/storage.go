@@ -0,0 +1,18 @@
+package storage
+
+const ConfigPrefix = "STORAGE_"
+
+const (
+	// BackendMongo stores the appointments in Mongo and caches them in Redis.
+	BackendMongo = "mongo"
+	// BackendPostgres stores the appointments in Postgres and caches them in
+	// Redis.
+	BackendPostgres = "postgres"
+	// BackendMemory keeps everything in the process, so the API runs without
+	// any external service. Nothing outlives the process.
+	BackendMemory = "memory"
+)
+
+type Config struct {
+	Backend string `env:"BACKEND, default=mongo"`
+}
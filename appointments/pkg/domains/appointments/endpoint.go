@@ -26,6 +26,22 @@ func CreateAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 	}
 }
 
+func GenerateAppointments(svc service.AppointmentServiceI) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(model.SlotTemplate)
+		if !ok {
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> SlotTemplate")
+		}
+
+		generateResponse, err := svc.GenerateAppointments(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return generateResponse, nil
+	}
+}
+
 func FindAppointmentByID(svc service.AppointmentServiceI) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(model.FindAppointmentsByIDRequest)
@@ -44,7 +60,12 @@ func FindAppointmentByID(svc service.AppointmentServiceI) endpoint.Endpoint {
 
 func FindAllAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		appResponse, err := svc.FindAllAppointments(ctx)
+		req, ok := request.(model.ListOptions)
+		if !ok {
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> ListOptions")
+		}
+
+		appResponse, err := svc.FindAllAppointments(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -69,6 +90,22 @@ func FindAppointmentByUser(svc service.AppointmentServiceI) endpoint.Endpoint {
 	}
 }
 
+func FindAppointmentByProfessional(svc service.AppointmentServiceI) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(model.FindAppByProfessional)
+		if !ok {
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> FindAppByProfessional")
+		}
+
+		appResponse, err := svc.FindAppByProfessionalID(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return appResponse, nil
+	}
+}
+
 func FindAppointmentBySalon(svc service.AppointmentServiceI) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(model.FindAppBySalon)
@@ -101,6 +138,22 @@ func UpdateAppointmentByUser(svc service.AppointmentServiceI) endpoint.Endpoint
 	}
 }
 
+func PatchAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(model.PatchAppointment)
+		if !ok {
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> PatchAppointment")
+		}
+
+		appResponse, err := svc.PatchAppointment(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return appResponse, nil
+	}
+}
+
 func MakeAppointmentByUser(svc service.AppointmentServiceI) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req, ok := request.(model.MakeAppointment)
@@ -135,7 +188,12 @@ func DeleteAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 
 func AvailableAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		app, err := svc.FindAvailableAppointments(ctx)
+		req, ok := request.(model.FindAvailable)
+		if !ok {
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> FindAvailable")
+		}
+
+		app, err := svc.FindAvailableAppointments(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -146,9 +204,9 @@ func AvailableAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 
 func CancelAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req, ok := request.(model.MakeAppointment)
+		req, ok := request.(model.CancelAppointment)
 		if !ok {
-			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> MakeAppointment")
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> CancelAppointment")
 		}
 
 		err := svc.CancelAppointment(ctx, req)
@@ -159,3 +217,19 @@ func CancelAppointment(svc service.AppointmentServiceI) endpoint.Endpoint {
 		return nil, nil
 	}
 }
+
+func ChangeAppointmentStatus(svc service.AppointmentServiceI) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(model.ChangeStatus)
+		if !ok {
+			return nil, errors.Wrap(appErr.ErrTypeAssertion, "cannot convert request -> ChangeStatus")
+		}
+
+		appResponse, err := svc.ChangeStatus(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return appResponse, nil
+	}
+}
@@ -19,6 +19,11 @@ var fakeAppResponse = model.AppResponse{
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
 }
 
+var fakeAppPage = model.AppPageResponse{
+	Items: []model.AppResponse{fakeAppResponse},
+	Total: 1,
+}
+
 var fakeUpsert = model.UpsertAppointment{
 	ID:              "629aac9c363519d9a9615369",
 	UserID:          1,
@@ -154,24 +159,24 @@ func TestFindAllAppointment(t *testing.T) {
 			name: "success",
 			args: args{
 				svc:     service.NewMockAppointmentServiceI(ctrl),
-				request: nil,
+				request: model.ListOptions{},
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().FindAllAppointments(context.Background()).Return([]model.AppResponse{fakeAppResponse}, nil)
+				s.EXPECT().FindAllAppointments(context.Background(), model.ListOptions{}).Return(&fakeAppPage, nil)
 			},
-			response: []model.AppResponse{fakeAppResponse},
+			response: &fakeAppPage,
 			err:      nil,
 		},
 		{
 			name: "fail, return error",
 			args: args{
 				svc:     service.NewMockAppointmentServiceI(ctrl),
-				request: nil,
+				request: model.ListOptions{},
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().FindAllAppointments(context.Background()).Return(nil, appErr.ErrDatabase)
+				s.EXPECT().FindAllAppointments(context.Background(), model.ListOptions{}).Return(nil, appErr.ErrDatabase)
 			},
 			response: nil,
 			err:      appErr.ErrDatabase,
@@ -210,9 +215,9 @@ func TestFindAppointmentByUser(t *testing.T) {
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().FindAppByUserID(ctx, model.FindAppByUser{ID: 1}).Return([]model.AppResponse{fakeAppResponse}, nil)
+				s.EXPECT().FindAppByUserID(ctx, model.FindAppByUser{ID: 1}).Return(&fakeAppPage, nil)
 			},
-			response: []model.AppResponse{fakeAppResponse},
+			response: &fakeAppPage,
 		},
 		{
 			name: "fail, return error",
@@ -260,9 +265,9 @@ func TestFindAppointmentBySalon(t *testing.T) {
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().FindAppBySalonID(ctx, model.FindAppBySalon{ID: fakeAppResponse.SalonID}).Return([]model.AppResponse{fakeAppResponse}, nil)
+				s.EXPECT().FindAppBySalonID(ctx, model.FindAppBySalon{ID: fakeAppResponse.SalonID}).Return(&fakeAppPage, nil)
 			},
-			response: []model.AppResponse{fakeAppResponse},
+			response: &fakeAppPage,
 		},
 		{
 			name: "fail, return error",
@@ -287,6 +292,56 @@ func TestFindAppointmentBySalon(t *testing.T) {
 	}
 }
 
+func TestFindAppointmentByProfessional(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	ctrl.Finish()
+	type args struct {
+		svc     *service.MockAppointmentServiceI
+		request interface{}
+		ctx     context.Context
+	}
+	tests := []struct {
+		name     string
+		args     args
+		init     func(s *service.MockAppointmentServiceI, ctx context.Context)
+		response interface{}
+		err      error
+	}{
+		{
+			name: "success",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: model.FindAppByProfessional{ID: 3},
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
+				s.EXPECT().FindAppByProfessionalID(ctx, model.FindAppByProfessional{ID: 3}).Return(&fakeAppPage, nil)
+			},
+			response: &fakeAppPage,
+		},
+		{
+			name: "fail, return error",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: model.FindAppByProfessional{ID: 3},
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
+				s.EXPECT().FindAppByProfessionalID(ctx, model.FindAppByProfessional{ID: 3}).Return(nil, appErr.ErrTypeAssertion)
+			},
+			err: appErr.ErrTypeAssertion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.init(tt.args.svc, tt.args.ctx)
+			response, err := FindAppointmentByProfessional(tt.args.svc)(tt.args.ctx, tt.args.request)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.response, response)
+		})
+	}
+}
+
 func TestUpdateAppointmentByUser(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
@@ -337,6 +392,51 @@ func TestUpdateAppointmentByUser(t *testing.T) {
 	}
 }
 
+func TestPatchAppointment(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	patch := model.PatchAppointment{ID: fakeUpsert.ID, Version: 1}
+	tests := []struct {
+		name     string
+		request  interface{}
+		init     func(s *service.MockAppointmentServiceI)
+		response interface{}
+		err      error
+	}{
+		{
+			name:    "success",
+			request: patch,
+			init: func(s *service.MockAppointmentServiceI) {
+				s.EXPECT().PatchAppointment(context.Background(), patch).Return(&fakeAppResponse, nil)
+			},
+			response: &fakeAppResponse,
+		},
+		{
+			name:    "fail, return error",
+			request: patch,
+			init: func(s *service.MockAppointmentServiceI) {
+				s.EXPECT().PatchAppointment(context.Background(), patch).Return(nil, appErr.ErrVersionMismatch)
+			},
+			err: appErr.ErrVersionMismatch,
+		},
+		{
+			name:    "fail, wrong request",
+			request: fakeUpsert,
+			init:    func(s *service.MockAppointmentServiceI) {},
+			err:     appErr.ErrTypeAssertion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewMockAppointmentServiceI(ctrl)
+			tt.init(svc)
+			response, err := PatchAppointment(svc)(context.Background(), tt.request)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.response, response)
+		})
+	}
+}
+
 func TestMakeAppointmentByUser(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
@@ -456,23 +556,23 @@ func TestAvailableAppointment(t *testing.T) {
 			name: "success",
 			args: args{
 				svc:     service.NewMockAppointmentServiceI(ctrl),
-				request: nil,
+				request: model.FindAvailable{},
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().FindAvailableAppointments(ctx).Return([]model.AppResponse{fakeAppResponse}, nil)
+				s.EXPECT().FindAvailableAppointments(ctx, model.FindAvailable{}).Return(&fakeAppPage, nil)
 			},
-			response: []model.AppResponse{fakeAppResponse},
+			response: &fakeAppPage,
 		},
 		{
 			name: "fail, return error",
 			args: args{
 				svc:     service.NewMockAppointmentServiceI(ctrl),
-				request: nil,
+				request: model.FindAvailable{},
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().FindAvailableAppointments(ctx).Return(nil, appErr.ErrDatabase)
+				s.EXPECT().FindAvailableAppointments(ctx, model.FindAvailable{}).Return(nil, appErr.ErrDatabase)
 			},
 			err: appErr.ErrDatabase,
 		},
@@ -506,11 +606,11 @@ func TestCancelAppointment(t *testing.T) {
 			name: "success",
 			args: args{
 				svc:     service.NewMockAppointmentServiceI(ctrl),
-				request: model.MakeAppointment{ID: fakeUpsert.ID, UserID: fakeAppResponse.UserID},
+				request: model.CancelAppointment{ID: fakeUpsert.ID, UserID: fakeAppResponse.UserID, Version: 1},
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().CancelAppointment(ctx, model.MakeAppointment{ID: fakeAppResponse.ID, UserID: fakeAppResponse.UserID}).Return(nil)
+				s.EXPECT().CancelAppointment(ctx, model.CancelAppointment{ID: fakeAppResponse.ID, UserID: fakeAppResponse.UserID, Version: 1}).Return(nil)
 			},
 			response: nil,
 		},
@@ -518,11 +618,11 @@ func TestCancelAppointment(t *testing.T) {
 			name: "fail, return error",
 			args: args{
 				svc:     service.NewMockAppointmentServiceI(ctrl),
-				request: model.MakeAppointment{ID: fakeUpsert.ID, UserID: fakeAppResponse.UserID},
+				request: model.CancelAppointment{ID: fakeUpsert.ID, UserID: fakeAppResponse.UserID, Version: 1},
 				ctx:     context.Background(),
 			},
 			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
-				s.EXPECT().CancelAppointment(ctx, model.MakeAppointment{ID: fakeAppResponse.ID, UserID: fakeAppResponse.UserID}).Return(appErr.ErrDatabase)
+				s.EXPECT().CancelAppointment(ctx, model.CancelAppointment{ID: fakeAppResponse.ID, UserID: fakeAppResponse.UserID, Version: 1}).Return(appErr.ErrDatabase)
 			},
 			err: appErr.ErrDatabase,
 		},
@@ -536,3 +636,133 @@ func TestCancelAppointment(t *testing.T) {
 		})
 	}
 }
+
+func TestChangeAppointmentStatus(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	ctrl.Finish()
+	type args struct {
+		svc     *service.MockAppointmentServiceI
+		request interface{}
+		ctx     context.Context
+	}
+	change := model.ChangeStatus{ID: fakeUpsert.ID, Status: model.StatusConfirmed}
+	tests := []struct {
+		name     string
+		args     args
+		init     func(s *service.MockAppointmentServiceI, ctx context.Context)
+		response interface{}
+		err      error
+	}{
+		{
+			name: "success",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: change,
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
+				s.EXPECT().ChangeStatus(ctx, change).Return(&fakeAppResponse, nil)
+			},
+			response: &fakeAppResponse,
+		},
+		{
+			name: "fail, return error",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: change,
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
+				s.EXPECT().ChangeStatus(ctx, change).Return(nil, appErr.ErrInvalidTransition)
+			},
+			err: appErr.ErrInvalidTransition,
+		},
+		{
+			name: "fail, invalid request",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: fakeUpsert,
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {},
+			err:  appErr.ErrTypeAssertion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.init(tt.args.svc, tt.args.ctx)
+			response, err := ChangeAppointmentStatus(tt.args.svc)(tt.args.ctx, tt.args.request)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.response, response)
+		})
+	}
+}
+
+func TestGenerateAppointments(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	ctrl.Finish()
+	type args struct {
+		svc     *service.MockAppointmentServiceI
+		request interface{}
+		ctx     context.Context
+	}
+	template := model.SlotTemplate{
+		SalonID:     1,
+		TimeZone:    "UTC",
+		From:        "2022-07-01",
+		To:          "2022-07-31",
+		SlotMinutes: 30,
+		Days:        []model.OpeningHours{{Weekday: "monday", Open: "09:00", Close: "18:00"}},
+	}
+	generated := &model.GenerateResponse{Created: 90}
+	tests := []struct {
+		name     string
+		args     args
+		init     func(s *service.MockAppointmentServiceI, ctx context.Context)
+		response interface{}
+		err      error
+	}{
+		{
+			name: "success",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: template,
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
+				s.EXPECT().GenerateAppointments(ctx, template).Return(generated, nil)
+			},
+			response: generated,
+		},
+		{
+			name: "fail, return error",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: template,
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {
+				s.EXPECT().GenerateAppointments(ctx, template).Return(nil, appErr.ErrDatabase)
+			},
+			err: appErr.ErrDatabase,
+		},
+		{
+			name: "fail, invalid request",
+			args: args{
+				svc:     service.NewMockAppointmentServiceI(ctrl),
+				request: fakeUpsert,
+				ctx:     context.Background(),
+			},
+			init: func(s *service.MockAppointmentServiceI, ctx context.Context) {},
+			err:  appErr.ErrTypeAssertion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.init(tt.args.svc, tt.args.ctx)
+			response, err := GenerateAppointments(tt.args.svc)(tt.args.ctx, tt.args.request)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.response, response)
+		})
+	}
+}
@@ -18,12 +18,36 @@ var (
 	// ErrEmptyRepository repository cannot be nil
 	ErrEmptyRepository = errors.New("empty repository")
 	// ErrTypeAssertion arises while trying to perform interface{}.(T)
-	ErrTypeAssertion  = errors.New("unable to execute type assertion")
-	ErrNotFound       = errors.New("Appointment not found")
+	ErrTypeAssertion = errors.New("unable to execute type assertion")
+	ErrNotFound      = errors.New("Appointment not found")
+	// ErrInvalidID arises when an appointment id is not a well-formed id
+	ErrInvalidID      = errors.New("Invalid appointment id")
 	ErrDatabase       = errors.New("An error happened in database")
 	ErrMemoryDatabase = errors.New("An error happened in memory database")
 	ErrInvalidPath    = errors.New("Cannot read path")
 	ErrInvalidBody    = errors.New("Invalid body")
+	ErrInvalidQuery   = errors.New("Invalid query parameters")
+	// ErrAlreadyBooked arises when booking an appointment that already has a user
+	ErrAlreadyBooked = errors.New("Appointment already booked")
+	// ErrNotOwner arises when a user cancels an appointment booked by another user
+	ErrNotOwner = errors.New("Appointment belongs to another user")
+	// ErrInvalidTransition arises when an appointment cannot move to the requested status
+	ErrInvalidTransition = errors.New("Invalid appointment status transition")
+	// ErrPastAppointment arises when a slot is created or moved to a date that already passed
+	ErrPastAppointment = errors.New("Appointment date must be in the future")
+	// ErrOverlappingAppointment arises when a slot overlaps another slot of the same salon
+	ErrOverlappingAppointment = errors.New("Appointment overlaps another slot")
+	// ErrRequestInProgress arises when a request is sent again with the idempotency key
+	// of a request that is still being processed
+	ErrRequestInProgress = errors.New("A request with this idempotency key is in progress")
+	// ErrVersionMismatch arises when an appointment is written on top of a version
+	// other than the one it was read at, i.e. someone else changed it meanwhile
+	ErrVersionMismatch = errors.New("Appointment was changed by someone else, read it again")
+	// ErrPreconditionRequired arises when a write does not say which version it applies to
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+	// ErrIdempotencyKeyReused arises when an idempotency key is sent again with
+	// a request other than the one it was first used for
+	ErrIdempotencyKeyReused = errors.New("Idempotency key was used by another request")
 )
 
 type errorResponse struct {
@@ -36,13 +60,35 @@ type restError map[error]errorResponse
 // RESTErrorBussines Errors you want to map to more meaning response for clients and set specific
 // HTTP status code should be included here
 var RESTErrorBussines = restError{
-	ErrNew:            {"Sorry, we cannot create a new appointment", http.StatusInternalServerError},
-	sql.ErrNoRows:     {"Record not found", http.StatusNotFound},
-	ErrNotFound:       {"Appointment not found", http.StatusNotFound},
-	ErrDatabase:       {"An error happened in database", http.StatusInternalServerError},
-	ErrInvalidPath:    {"Cannot read path", http.StatusBadRequest},
-	ErrInvalidBody:    {"Invalid body", http.StatusBadRequest},
-	ErrMemoryDatabase: {"Memory Database error", http.StatusBadRequest},
+	ErrNew:                    {"Sorry, we cannot create a new appointment", http.StatusInternalServerError},
+	sql.ErrNoRows:             {"Record not found", http.StatusNotFound},
+	ErrNotFound:               {"Appointment not found", http.StatusNotFound},
+	ErrInvalidID:              {"Invalid appointment id", http.StatusBadRequest},
+	ErrDatabase:               {"An error happened in database", http.StatusInternalServerError},
+	ErrInvalidPath:            {"Cannot read path", http.StatusBadRequest},
+	ErrInvalidBody:            {"Invalid body", http.StatusBadRequest},
+	ErrInvalidQuery:           {"Invalid query parameters", http.StatusBadRequest},
+	ErrMemoryDatabase:         {"Memory Database error", http.StatusBadRequest},
+	ErrAlreadyBooked:          {"Appointment already booked", http.StatusConflict},
+	ErrNotOwner:               {"Appointment belongs to another user", http.StatusForbidden},
+	ErrInvalidTransition:      {"Invalid appointment status transition", http.StatusConflict},
+	ErrPastAppointment:        {"Appointment date must be in the future", http.StatusUnprocessableEntity},
+	ErrOverlappingAppointment: {"Appointment overlaps another slot", http.StatusConflict},
+	ErrRequestInProgress:      {"A request with this idempotency key is in progress", http.StatusConflict},
+	ErrVersionMismatch:        {"Appointment was changed by someone else, read it again", http.StatusPreconditionFailed},
+	ErrPreconditionRequired:   {"If-Match header is required", http.StatusPreconditionRequired},
+	ErrIdempotencyKeyReused:   {"Idempotency key was used by another request", http.StatusUnprocessableEntity},
+}
+
+// Maps reports whether err is one of the errors with a response of its own.
+func (re restError) Maps(err error) bool {
+	for rErr := range re {
+		if errors.Is(err, rErr) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (re restError) ErrorProcess(err error) (string, int) {
@@ -0,0 +1,100 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+const (
+	HeaderVersion        = "version"
+	HeaderIdempotencyKey = "idempotency-key"
+)
+
+// Channel is the broker channel events are published on.
+// It is implemented by *amqp.Channel.
+type Channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// ConfirmChannel is a Channel that can be put in confirm mode.
+// It is implemented by *amqp.Channel.
+type ConfirmChannel interface {
+	Channel
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+}
+
+// AMQPPublisher publishes events to a topic exchange with the event type as
+// routing key, so consumers can bind to "appointment.#" or to a single type.
+// The event id is sent as message id and idempotency key.
+type AMQPPublisher struct {
+	ch       Channel
+	exchange string
+	confirms chan amqp.Confirmation
+}
+
+// NewAMQPPublisher declares the topic exchange and returns a publisher for it.
+func NewAMQPPublisher(ch Channel, exchange string) (*AMQPPublisher, error) {
+	if err := ch.ExchangeDeclare(exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return nil, errors.Wrapf(err, "declare exchange %s", exchange)
+	}
+
+	return &AMQPPublisher{ch: ch, exchange: exchange}, nil
+}
+
+// NewConfirmPublisher is like NewAMQPPublisher, but puts the channel in
+// confirm mode and makes Publish wait for the broker to take the event.
+// Publish must not be called concurrently.
+func NewConfirmPublisher(ch ConfirmChannel, exchange string) (*AMQPPublisher, error) {
+	p, err := NewAMQPPublisher(ch, exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		return nil, errors.Wrap(err, "enable publisher confirms")
+	}
+	p.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+
+	return p, nil
+}
+
+func (p *AMQPPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	err = p.ch.Publish(p.exchange, string(e.Type), false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    e.ID,
+		Type:         string(e.Type),
+		Timestamp:    e.OccurredAt,
+		Headers: amqp.Table{
+			HeaderVersion:        int32(e.Version),
+			HeaderIdempotencyKey: e.ID,
+		},
+		Body: body,
+	})
+	if err != nil || p.confirms == nil {
+		return err
+	}
+
+	select {
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			return errors.New("channel closed before the publish was confirmed")
+		}
+		if !confirm.Ack {
+			return errors.Errorf("broker refused event %s", e.ID)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,127 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChannel records the declared exchanges and published messages.
+type fakeChannel struct {
+	kinds     map[string]string
+	keys      []string
+	published []amqp.Publishing
+	err       error
+}
+
+func (c *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	if c.kinds == nil {
+		c.kinds = make(map[string]string)
+	}
+	c.kinds[name] = kind
+	return c.err
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.keys = append(c.keys, exchange+"/"+key)
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func TestNewAMQPPublisher(t *testing.T) {
+	ch := &fakeChannel{}
+	_, err := NewAMQPPublisher(ch, "appointments.events")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"appointments.events": amqp.ExchangeTopic}, ch.kinds)
+
+	_, err = NewAMQPPublisher(&fakeChannel{err: errors.New("channel closed")}, "appointments.events")
+	assert.Error(t, err)
+}
+
+func TestAMQPPublisher_Publish(t *testing.T) {
+	ch := &fakeChannel{}
+	publisher, err := NewAMQPPublisher(ch, "appointments.events")
+	require.NoError(t, err)
+	e := New(TypeBooked, model.Appointment{
+		ID:              "62b65300e1d7eab1ea9a681d",
+		UserID:          1,
+		SalonID:         1,
+		AppointmentDate: time.Date(2022, time.June, 23, 21, 0, 0, 0, time.UTC),
+		Status:          model.StatusBooked,
+	})
+
+	require.NoError(t, publisher.Publish(context.Background(), e))
+	require.Len(t, ch.published, 1)
+	msg := ch.published[0]
+	assert.Equal(t, []string{"appointments.events/appointment.booked"}, ch.keys)
+	assert.Equal(t, e.ID, msg.MessageId)
+	assert.Equal(t, "appointment.booked", msg.Type)
+	assert.Equal(t, "application/json", msg.ContentType)
+	assert.Equal(t, amqp.Persistent, msg.DeliveryMode)
+	assert.Equal(t, int32(Version), msg.Headers["version"])
+
+	var got Event
+	require.NoError(t, json.Unmarshal(msg.Body, &got))
+	assert.Equal(t, e.ID, got.ID)
+	assert.Equal(t, Version, got.Version)
+	assert.Equal(t, "62b65300e1d7eab1ea9a681d", got.Data.ID)
+	assert.Equal(t, model.StatusBooked, got.Data.Status)
+}
+
+func TestStatusType(t *testing.T) {
+	assert.Equal(t, TypeConfirmed, StatusType(model.StatusConfirmed))
+	assert.Equal(t, TypeCheckedIn, StatusType(model.StatusCheckedIn))
+	assert.Equal(t, TypeNoShow, StatusType(model.StatusNoShow))
+}
+
+// fakeConfirmChannel confirms every publish with ack.
+type fakeConfirmChannel struct {
+	fakeChannel
+	ack      bool
+	confirms chan amqp.Confirmation
+}
+
+func (c *fakeConfirmChannel) Confirm(noWait bool) error {
+	return nil
+}
+
+func (c *fakeConfirmChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	c.confirms = confirm
+	return confirm
+}
+
+func (c *fakeConfirmChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	_ = c.fakeChannel.Publish(exchange, key, mandatory, immediate, msg)
+	c.confirms <- amqp.Confirmation{DeliveryTag: uint64(len(c.published)), Ack: c.ack}
+	return nil
+}
+
+func TestConfirmPublisher_Publish(t *testing.T) {
+	tests := []struct {
+		name    string
+		ack     bool
+		wantErr bool
+	}{
+		{name: "success, broker confirmed the event", ack: true},
+		{name: "fail, broker refused the event", ack: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &fakeConfirmChannel{ack: tt.ack}
+			publisher, err := NewConfirmPublisher(ch, "appointments.events")
+			require.NoError(t, err)
+
+			err = publisher.Publish(context.Background(), New(TypeCreated, model.Appointment{ID: "62b65300e1d7eab1ea9a681d"}))
+			assert.Equal(t, tt.wantErr, err != nil)
+			require.Len(t, ch.published, 1)
+			assert.Equal(t, ch.published[0].MessageId, ch.published[0].Headers[HeaderIdempotencyKey])
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/google/uuid"
+)
+
+// Version is the version of the event schema, bumped on breaking changes to
+// Event or its data so consumers can tell them apart.
+const Version = 1
+
+// Type names what happened to an appointment, it is also the routing key
+// events are published with.
+type Type string
+
+const (
+	TypeCreated   Type = "appointment.created"
+	TypeUpdated   Type = "appointment.updated"
+	TypeBooked    Type = "appointment.booked"
+	TypeCancelled Type = "appointment.cancelled"
+	TypeDeleted   Type = "appointment.deleted"
+	TypeConfirmed Type = "appointment.confirmed"
+	TypeCheckedIn Type = "appointment.checked_in"
+	TypeCompleted Type = "appointment.completed"
+	TypeNoShow    Type = "appointment.no_show"
+)
+
+// StatusType returns the type of the event emitted when an appointment
+// moves to status.
+func StatusType(status model.Status) Type {
+	return Type("appointment." + string(status))
+}
+
+type Event struct {
+	ID         string            `json:"id"`
+	Type       Type              `json:"type"`
+	Version    int               `json:"version"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Data       model.AppResponse `json:"data"`
+}
+
+func New(t Type, app model.Appointment) Event {
+	return Event{
+		ID:         uuid.NewString(),
+		Type:       t,
+		Version:    Version,
+		OccurredAt: time.Now().UTC(),
+		Data:       model.NewAppResponse(app),
+	}
+}
+
+// Publisher emits appointment events to the other services.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Discard is a Publisher that drops every event.
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(context.Context, Event) error { return nil }
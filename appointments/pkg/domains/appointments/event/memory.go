@@ -0,0 +1,55 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps the published events in memory, it is meant for
+// tests.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Fail makes every following Publish return err.
+func (p *MemoryPublisher) Fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, e Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+
+	p.events = append(p.events, e)
+	return nil
+}
+
+// Events returns the published events in order.
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Event(nil), p.events...)
+}
+
+// Types returns the type of every published event in order.
+func (p *MemoryPublisher) Types() []Type {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var types []Type
+	for _, e := range p.events {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
@@ -0,0 +1,128 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/pkg/errors"
+)
+
+// OutboxPublisher stores events in the outbox instead of sending them, so
+// that, published within a transaction, an event is stored if and only if
+// its change is. The Relay sends them to the broker later.
+type OutboxPublisher struct {
+	outbox repository.OutboxI
+}
+
+func NewOutboxPublisher(outbox repository.OutboxI) *OutboxPublisher {
+	return &OutboxPublisher{outbox: outbox}
+}
+
+func (p *OutboxPublisher) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return p.outbox.AddOutbox(ctx, model.OutboxEntry{
+		Key:       e.ID,
+		Type:      string(e.Type),
+		Payload:   payload,
+		CreatedAt: e.OccurredAt,
+	})
+}
+
+// Relay publishes the outbox entries in the order they were stored. An
+// entry may be published more than once, when marking it sent fails, so
+// consumers must drop duplicates by the event id. An entry that fails
+// maxAttempts times is parked, so it stops holding back the ones behind it.
+type Relay struct {
+	outbox      repository.OutboxI
+	publisher   Publisher
+	batch       int
+	interval    time.Duration
+	maxAttempts int
+}
+
+func NewRelay(outbox repository.OutboxI, publisher Publisher, batch int, interval time.Duration,
+	maxAttempts int) *Relay {
+	return &Relay{
+		outbox:      outbox,
+		publisher:   publisher,
+		batch:       batch,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Run relays the pending entries until ctx is done. It polls the outbox
+// every interval, unless the last batch was full and more entries wait.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		sent, err := r.Relay(ctx)
+		if err != nil {
+			log.Printf("Cannot relay the outbox %v", err)
+		}
+
+		wait := r.interval
+		if err == nil && sent == r.batch {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Relay publishes one batch of pending entries and returns how many were
+// sent. It stops at the first failure, so an entry is never published before
+// the ones stored ahead of it, and the failed entry is retried on the next
+// batch. Once an entry has failed maxAttempts times it is parked instead,
+// and the batch goes on without it.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	entries, err := r.outbox.PendingOutbox(ctx, r.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, entry := range entries {
+		if err := r.publish(ctx, entry); err != nil {
+			if entry.Attempts+1 >= r.maxAttempts {
+				if err := r.outbox.ParkOutbox(ctx, entry.ID, err.Error()); err != nil {
+					return sent, err
+				}
+				log.Printf("Parked the outbox entry %s after %d attempts %v", entry.ID, entry.Attempts+1, err)
+				continue
+			}
+
+			if err := r.outbox.MarkOutboxFailed(ctx, entry.ID, err.Error()); err != nil {
+				log.Printf("Cannot mark the outbox entry %s failed %v", entry.ID, err)
+			}
+			return sent, errors.Wrapf(err, "relay %s %s", entry.Type, entry.Key)
+		}
+
+		if err := r.outbox.MarkOutboxSent(ctx, entry.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (r *Relay) publish(ctx context.Context, entry model.OutboxEntry) error {
+	var e Event
+	if err := json.Unmarshal(entry.Payload, &e); err != nil {
+		return err
+	}
+
+	return r.publisher.Publish(ctx, e)
+}
@@ -0,0 +1,146 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeEvent = Event{
+	ID:         "3f1c8a5e-2c1d-4a3b-9f61-7d2b3c4d5e6f",
+	Type:       TypeBooked,
+	Version:    Version,
+	OccurredAt: time.Date(2022, time.June, 23, 21, 0, 0, 0, time.UTC),
+	Data:       model.AppResponse{ID: "62b65300e1d7eab1ea9a681d", UserID: 1, SalonID: 1, Status: model.StatusBooked},
+}
+
+func fakeEntry(t *testing.T, id string, e Event) model.OutboxEntry {
+	payload, err := json.Marshal(e)
+	require.NoError(t, err)
+	return model.OutboxEntry{ID: id, Key: e.ID, Type: string(e.Type), Payload: payload, CreatedAt: e.OccurredAt}
+}
+
+func TestOutboxPublisher_Publish(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	outbox := repository.NewMockOutboxI(ctrl)
+	outbox.EXPECT().AddOutbox(context.Background(), fakeEntry(t, "", fakeEvent)).Return(nil)
+
+	err := NewOutboxPublisher(outbox).Publish(context.Background(), fakeEvent)
+	assert.NoError(t, err)
+}
+
+func TestRelay_Relay(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	second := fakeEvent
+	second.ID = "9a7b6c5d-4e3f-4a1b-8c2d-1e0f9a8b7c6d"
+	second.Type = TypeCancelled
+	entries := []model.OutboxEntry{
+		fakeEntry(t, "62b65300e1d7eab1ea9a6800", fakeEvent),
+		fakeEntry(t, "62b65300e1d7eab1ea9a6801", second),
+	}
+	poison := model.OutboxEntry{ID: "62b65300e1d7eab1ea9a67ff", Type: string(TypeBooked), Payload: []byte("{"), Attempts: 9}
+	tests := []struct {
+		name    string
+		init    func(*repository.MockOutboxI, *MemoryPublisher)
+		sent    int
+		events  []Type
+		wantErr bool
+	}{
+		{
+			name: "success, relayed the entries in order",
+			init: func(o *repository.MockOutboxI, p *MemoryPublisher) {
+				o.EXPECT().PendingOutbox(context.Background(), 10).Return(entries, nil)
+				gomock.InOrder(
+					o.EXPECT().MarkOutboxSent(context.Background(), entries[0].ID).Return(nil),
+					o.EXPECT().MarkOutboxSent(context.Background(), entries[1].ID).Return(nil),
+				)
+			},
+			sent:   2,
+			events: []Type{TypeBooked, TypeCancelled},
+		},
+		{
+			name: "fail, stopped at the entry that cannot be published",
+			init: func(o *repository.MockOutboxI, p *MemoryPublisher) {
+				p.Fail(errors.New("channel closed"))
+				o.EXPECT().PendingOutbox(context.Background(), 10).Return(entries, nil)
+				o.EXPECT().MarkOutboxFailed(context.Background(), entries[0].ID, "channel closed").Return(nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "success, parked the entry out of attempts and relayed the ones behind it",
+			init: func(o *repository.MockOutboxI, p *MemoryPublisher) {
+				o.EXPECT().PendingOutbox(context.Background(), 10).
+					Return(append([]model.OutboxEntry{poison}, entries...), nil)
+				gomock.InOrder(
+					o.EXPECT().ParkOutbox(context.Background(), poison.ID, "unexpected end of JSON input").Return(nil),
+					o.EXPECT().MarkOutboxSent(context.Background(), entries[0].ID).Return(nil),
+					o.EXPECT().MarkOutboxSent(context.Background(), entries[1].ID).Return(nil),
+				)
+			},
+			sent:   2,
+			events: []Type{TypeBooked, TypeCancelled},
+		},
+		{
+			name: "fail, cannot park the entry",
+			init: func(o *repository.MockOutboxI, p *MemoryPublisher) {
+				o.EXPECT().PendingOutbox(context.Background(), 10).
+					Return(append([]model.OutboxEntry{poison}, entries...), nil)
+				o.EXPECT().ParkOutbox(context.Background(), poison.ID, "unexpected end of JSON input").
+					Return(errors.New("database down"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail, cannot read the outbox",
+			init: func(o *repository.MockOutboxI, p *MemoryPublisher) {
+				o.EXPECT().PendingOutbox(context.Background(), 10).Return(nil, errors.New("database down"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := repository.NewMockOutboxI(ctrl)
+			publisher := NewMemoryPublisher()
+			tt.init(outbox, publisher)
+
+			sent, err := NewRelay(outbox, publisher, 10, time.Second, 10).Relay(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.sent, sent)
+			assert.Equal(t, tt.events, publisher.Types())
+		})
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	outbox := repository.NewMockOutboxI(ctrl)
+	publisher := NewMemoryPublisher()
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := fakeEntry(t, "62b65300e1d7eab1ea9a6800", fakeEvent)
+	gomock.InOrder(
+		outbox.EXPECT().PendingOutbox(ctx, 1).Return([]model.OutboxEntry{entry}, nil),
+		outbox.EXPECT().MarkOutboxSent(ctx, entry.ID).Return(nil),
+		// the batch was full, so the outbox is read again right away
+		outbox.EXPECT().PendingOutbox(ctx, 1).DoAndReturn(
+			func(context.Context, int) ([]model.OutboxEntry, error) {
+				cancel()
+				return nil, nil
+			}),
+	)
+
+	assert.NoError(t, NewRelay(outbox, publisher, 1, time.Hour, 10).Run(ctx))
+	assert.Equal(t, []Event{fakeEvent}, publisher.Events())
+}
@@ -0,0 +1,99 @@
+package appointments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context carrying the idempotency key the
+// request was sent with.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key of the request, if any.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+// Idempotent makes next run once per idempotency key, a request sent again
+// with the same key gets the response of the first one, as JSON. Keys are
+// scoped by operation and requests without a key always run. A key sent
+// again with a different request fails with ErrIdempotencyKeyReused instead
+// of getting the response of another request. Failed requests release their
+// key, so they can be retried.
+func Idempotent(store repository.IdempotencyI, operation string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		if store == nil {
+			return next
+		}
+
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			key, ok := IdempotencyKey(ctx)
+			if !ok {
+				return next(ctx, request)
+			}
+
+			key = operation + "_" + key
+			fingerprint, err := requestFingerprint(request)
+			if err != nil {
+				return nil, err
+			}
+
+			stored, done, err := store.ReserveKey(key, fingerprint)
+			if err != nil {
+				return nil, err
+			}
+
+			if done {
+				return json.RawMessage(stored), nil
+			}
+
+			response, err := next(ctx, request)
+			if err != nil {
+				if err := store.ReleaseKey(key); err != nil {
+					log.Printf("Cannot release the idempotency key %s %v", key, err)
+				}
+				return nil, err
+			}
+
+			body, err := json.Marshal(response)
+			if err == nil {
+				err = store.CompleteKey(key, fingerprint, body)
+			}
+			if err != nil {
+				log.Printf("Cannot store the response of %s %v", key, err)
+			}
+
+			return response, nil
+		}
+	}
+}
+
+// requestFingerprint identifies a request by the hash of its JSON, which
+// holds the appointment id from the path along with the body, so a key sent
+// again with another request is told apart from a retry.
+func requestFingerprint(request interface{}) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
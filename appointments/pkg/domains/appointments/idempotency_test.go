@@ -0,0 +1,144 @@
+package appointments
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotent(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+
+	body, _ := json.Marshal(&fakeAppResponse)
+	keyed := WithIdempotencyKey(context.Background(), "key")
+	fingerprint, err := requestFingerprint(fakeUpsert)
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		init     func(store *repository.MockIdempotencyI)
+		err      error
+		calls    int
+		response interface{}
+	}{
+		{
+			name:     "success, without key",
+			ctx:      context.Background(),
+			init:     func(store *repository.MockIdempotencyI) {},
+			calls:    1,
+			response: &fakeAppResponse,
+		},
+		{
+			name: "success, first request stores the response",
+			ctx:  keyed,
+			init: func(store *repository.MockIdempotencyI) {
+				store.EXPECT().ReserveKey("create_key", fingerprint).Return(nil, false, nil)
+				store.EXPECT().CompleteKey("create_key", fingerprint, body).Return(nil)
+			},
+			calls:    1,
+			response: &fakeAppResponse,
+		},
+		{
+			name: "success, duplicate returns the stored response",
+			ctx:  keyed,
+			init: func(store *repository.MockIdempotencyI) {
+				store.EXPECT().ReserveKey("create_key", fingerprint).Return(body, true, nil)
+			},
+			response: json.RawMessage(body),
+		},
+		{
+			name: "success, response not stored",
+			ctx:  keyed,
+			init: func(store *repository.MockIdempotencyI) {
+				store.EXPECT().ReserveKey("create_key", fingerprint).Return(nil, false, nil)
+				store.EXPECT().CompleteKey("create_key", fingerprint, body).Return(appErr.ErrMemoryDatabase)
+			},
+			calls:    1,
+			response: &fakeAppResponse,
+		},
+		{
+			name: "fail, key reused by another request",
+			ctx:  keyed,
+			init: func(store *repository.MockIdempotencyI) {
+				store.EXPECT().ReserveKey("create_key", fingerprint).Return(nil, false, appErr.ErrIdempotencyKeyReused)
+			},
+			err: appErr.ErrIdempotencyKeyReused,
+		},
+		{
+			name: "fail, request in progress",
+			ctx:  keyed,
+			init: func(store *repository.MockIdempotencyI) {
+				store.EXPECT().ReserveKey("create_key", fingerprint).Return(nil, false, appErr.ErrRequestInProgress)
+			},
+			err: appErr.ErrRequestInProgress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMockIdempotencyI(ctrl)
+			tt.init(store)
+			calls := 0
+			next := func(ctx context.Context, request interface{}) (interface{}, error) {
+				calls++
+				return &fakeAppResponse, nil
+			}
+
+			response, err := Idempotent(store, "create")(next)(tt.ctx, fakeUpsert)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.response, response)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+
+	t.Run("fail, releases the key", func(t *testing.T) {
+		store := repository.NewMockIdempotencyI(ctrl)
+		store.EXPECT().ReserveKey("create_key", fingerprint).Return(nil, false, nil)
+		store.EXPECT().ReleaseKey("create_key").Return(nil)
+		next := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return nil, appErr.ErrNew
+		}
+
+		response, err := Idempotent(store, "create")(next)(keyed, fakeUpsert)
+		assert.ErrorIs(t, err, appErr.ErrNew)
+		assert.Nil(t, response)
+	})
+
+	t.Run("success, without store", func(t *testing.T) {
+		next := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return &fakeAppResponse, nil
+		}
+
+		response, err := Idempotent(nil, "create")(next)(keyed, fakeUpsert)
+		assert.NoError(t, err)
+		assert.Equal(t, &fakeAppResponse, response)
+	})
+}
+
+func Test_requestFingerprint(t *testing.T) {
+	first, err := requestFingerprint(model.DeleteAppointment{ID: "629aac9c363519d9a9615369", Version: 1})
+	require.NoError(t, err)
+	again, err := requestFingerprint(model.DeleteAppointment{ID: "629aac9c363519d9a9615369", Version: 1})
+	require.NoError(t, err)
+	other, err := requestFingerprint(model.DeleteAppointment{ID: "629aac9c363519d9a9615370", Version: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first, other, "the same body on another appointment is another request")
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	_, ok := IdempotencyKey(WithIdempotencyKey(context.Background(), ""))
+	assert.False(t, ok)
+
+	key, ok := IdempotencyKey(WithIdempotencyKey(context.Background(), "key"))
+	assert.True(t, ok)
+	assert.Equal(t, "key", key)
+}
@@ -0,0 +1,23 @@
+package log
+
+import (
+	"log"
+)
+
+// StdLog writes the events to the standard logger, for when Splunk is not
+// available.
+type StdLog struct{}
+
+func NewStdLog() *StdLog {
+	return &StdLog{}
+}
+
+func (d *StdLog) Log(data interface{}) error {
+	log.Printf("%v", data)
+	return nil
+}
+
+// LogWithTime is Log, the standard logger already prefixes the time.
+func (d *StdLog) LogWithTime(data interface{}) error {
+	return d.Log(data)
+}
@@ -4,13 +4,75 @@ import (
 	"time"
 )
 
+// SlotDuration is how long an appointment slot lasts when no duration is given.
+const SlotDuration = 30 * time.Minute
+
+// AnyVersion is the version of a write that applies to the appointment at
+// whatever version it is stored, as with If-Match: *.
+const AnyVersion int64 = -1
+
 type Appointment struct {
-	ID              string    `bson:"_id,omitempty"`
-	UserID          int       `bson:"user_id"`
-	SalonID         int       `bson:"salon_id"`
-	AppointmentDate time.Time `bson:"appointment_date"`
+	ID              string      `bson:"_id,omitempty"`
+	UserID          int         `bson:"user_id"`
+	SalonID         int         `bson:"salon_id"`
+	ProfessionalID  int         `bson:"professional_id"`
+	AppointmentDate time.Time   `bson:"appointment_date"`
+	EndDate         time.Time   `bson:"end_date,omitempty"`
+	ServiceType     ServiceType `bson:"service_type,omitempty"`
+	PriceCents      int64       `bson:"price_cents,omitempty"`
+	Status          Status      `bson:"status,omitempty"`
+	// Version is bumped on every write. Appointments stored before the
+	// version field existed are at version 0.
+	Version int64 `bson:"version"`
 }
 
 func NewAppointment(appointment UpsertAppointment) Appointment {
-	return Appointment(appointment)
+	duration := SlotDuration
+	if appointment.DurationMinutes > 0 {
+		duration = time.Duration(appointment.DurationMinutes) * time.Minute
+	}
+
+	app := Appointment{
+		ID:              appointment.ID,
+		UserID:          appointment.UserID,
+		SalonID:         appointment.SalonID,
+		ProfessionalID:  appointment.ProfessionalID,
+		AppointmentDate: appointment.AppointmentDate,
+		EndDate:         appointment.AppointmentDate.Add(duration),
+		ServiceType:     appointment.ServiceType,
+		PriceCents:      appointment.PriceCents,
+		Version:         appointment.Version,
+	}
+	app.Status = app.State()
+
+	return app
+}
+
+// State returns the appointment status. Appointments stored before the
+// status field existed are derived from UserID, where 0 means available.
+func (a Appointment) State() Status {
+	if a.Status != "" {
+		return a.Status
+	}
+
+	if a.UserID == 0 {
+		return StatusAvailable
+	}
+
+	return StatusBooked
+}
+
+// End returns when the appointment finishes. Appointments stored before the
+// end date existed last SlotDuration.
+func (a Appointment) End() time.Time {
+	if a.EndDate.IsZero() {
+		return a.AppointmentDate.Add(SlotDuration)
+	}
+
+	return a.EndDate
+}
+
+// Duration returns how long the appointment lasts.
+func (a Appointment) Duration() time.Duration {
+	return a.End().Sub(a.AppointmentDate)
 }
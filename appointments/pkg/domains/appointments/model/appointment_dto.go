@@ -8,11 +8,20 @@ type UpsertAppointment struct {
 	ID              string    `json:"id,omitempty" example:"62b65300e1d7eab1ea9a681d"`
 	UserID          int       `json:"user_id" example:"1"`
 	SalonID         int       `json:"salon_id" validate:"required" example:"1"`
+	ProfessionalID  int       `json:"professional_id,omitempty" example:"3"`
 	AppointmentDate time.Time `json:"appointment_date" validate:"required" example:"2022-06-23T21:12:02.000000001Z"`
+	// DurationMinutes defaults to SlotDuration when it is not given.
+	DurationMinutes int         `json:"duration_minutes,omitempty" validate:"omitempty,min=1,max=1440" example:"45"`
+	ServiceType     ServiceType `json:"service_type,omitempty" validate:"omitempty,oneof=haircut manicure coloring" example:"haircut"`
+	PriceCents      int64       `json:"price_cents,omitempty" validate:"min=0" example:"4500"`
+	// Version is the version the update applies to, it is ignored on create.
+	Version int64 `json:"version,omitempty" example:"3"`
 }
 
 type DeleteAppointment struct {
 	ID string `json:"id"`
+	// Version is the version the delete applies to.
+	Version int64 `json:"version"`
 }
 
 type FindAppointmentsByIDRequest struct {
@@ -21,17 +30,37 @@ type FindAppointmentsByIDRequest struct {
 
 type FindAppByUser struct {
 	ID int `json:"id"`
+	ListOptions
 }
 
 type FindAppBySalon struct {
 	ID int `json:"id"`
+	ListOptions
+}
+
+type FindAppByProfessional struct {
+	ID int `json:"id"`
+	ListOptions
+}
+
+type FindAvailable struct {
+	SalonIDs     []int         `json:"salon_id"`
+	ServiceTypes []ServiceType `json:"service_type" validate:"dive,oneof=haircut manicure coloring"`
+	ListOptions
 }
 
 type AppResponse struct {
-	ID              string    `json:"id" example:"62b65300e1d7eab1ea9a681d"`
-	UserID          int       `json:"user_id" example:"1"`
-	SalonID         int       `json:"salon_id" example:"1"`
-	AppointmentDate time.Time `json:"appointment_date" example:"2022-06-23T21:12:02.000000001Z"`
+	ID              string      `json:"id" example:"62b65300e1d7eab1ea9a681d"`
+	UserID          int         `json:"user_id" example:"1"`
+	SalonID         int         `json:"salon_id" example:"1"`
+	ProfessionalID  int         `json:"professional_id,omitempty" example:"3"`
+	AppointmentDate time.Time   `json:"appointment_date" example:"2022-06-23T21:12:02.000000001Z"`
+	EndDate         time.Time   `json:"end_date" example:"2022-06-23T21:42:02.000000001Z"`
+	DurationMinutes int         `json:"duration_minutes" example:"30"`
+	ServiceType     ServiceType `json:"service_type,omitempty" example:"haircut"`
+	PriceCents      int64       `json:"price_cents,omitempty" example:"4500"`
+	Status          Status      `json:"status" example:"booked"`
+	Version         int64       `json:"version" example:"3"`
 }
 
 type MakeAppointment struct {
@@ -39,8 +68,66 @@ type MakeAppointment struct {
 	UserID int    `json:"user_id" validate:"required" example:"1"`
 }
 
+type CancelAppointment struct {
+	ID     string `json:"id" validate:"required" example:"62b65300e1d7eab1ea9a681d"`
+	UserID int    `json:"user_id" validate:"required" example:"1"`
+	// Version is the version the cancel applies to.
+	Version int64 `json:"version" example:"3"`
+}
+
+type ChangeStatus struct {
+	ID     string `json:"id" validate:"required" example:"62b65300e1d7eab1ea9a681d"`
+	Status Status `json:"status" validate:"required" example:"confirmed"`
+}
+
+type BookAppointment struct {
+	UserID int `json:"user_id" example:"1"`
+}
+
 func NewAppResponse(appointment Appointment) AppResponse {
-	return AppResponse(appointment)
+	return AppResponse{
+		ID:              appointment.ID,
+		UserID:          appointment.UserID,
+		SalonID:         appointment.SalonID,
+		ProfessionalID:  appointment.ProfessionalID,
+		AppointmentDate: appointment.AppointmentDate,
+		EndDate:         appointment.End(),
+		DurationMinutes: int(appointment.Duration() / time.Minute),
+		ServiceType:     appointment.ServiceType,
+		PriceCents:      appointment.PriceCents,
+		Status:          appointment.State(),
+		Version:         appointment.Version,
+	}
+}
+
+// NewUpsertAppointment returns the request that would write appointment as
+// it is.
+func NewUpsertAppointment(appointment Appointment) UpsertAppointment {
+	return UpsertAppointment{
+		ID:              appointment.ID,
+		UserID:          appointment.UserID,
+		SalonID:         appointment.SalonID,
+		ProfessionalID:  appointment.ProfessionalID,
+		AppointmentDate: appointment.AppointmentDate,
+		DurationMinutes: int(appointment.Duration() / time.Minute),
+		ServiceType:     appointment.ServiceType,
+		PriceCents:      appointment.PriceCents,
+		Version:         appointment.Version,
+	}
+}
+
+type AppPageResponse struct {
+	Items    []AppResponse `json:"items"`
+	NextPage string        `json:"next_page,omitempty" example:"eyJkIjoiMjAyMi0wNi0yM1QyMToxMjowMloiLCJpZCI6IjYyYjY1MzAwZTFkN2VhYjFlYTlhNjgxZCJ9"`
+	Total    int64         `json:"total" example:"42"`
+}
+
+func NewAppPageResponse(page AppointmentPage) AppPageResponse {
+	return AppPageResponse{
+		Items:    NewAppResponseSlice(page.Appointments),
+		NextPage: page.NextPage,
+		Total:    page.Total,
+	}
 }
 
 func NewAppResponseSlice(appointment []Appointment) []AppResponse {
@@ -11,16 +11,25 @@ var fakeAppointmentReponse = AppResponse{
 	UserID:          1,
 	SalonID:         1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	DurationMinutes: 30,
+	Status:          StatusBooked,
 }
 
 var fakeAppResponsessWithoutUserID = AppResponse{
 	SalonID:         1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	DurationMinutes: 30,
+	Status:          StatusAvailable,
 }
 
 var fakeUpsertAppResponseWithoutSalonID = AppResponse{
 	UserID:          1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	DurationMinutes: 30,
+	Status:          StatusBooked,
 }
 
 func TestNewAppResponse(t *testing.T) {
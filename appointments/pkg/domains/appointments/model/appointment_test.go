@@ -11,16 +11,22 @@ var fakeAppointment = Appointment{
 	UserID:          1,
 	SalonID:         1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	Status:          StatusBooked,
 }
 
 var fakeAppointmentWithoutUserID = Appointment{
 	SalonID:         1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	Status:          StatusAvailable,
 }
 
 var fakeAppointmentWithoutSalonID = Appointment{
 	UserID:          1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	Status:          StatusBooked,
 }
 
 func TestNewAppointment(t *testing.T) {
@@ -63,6 +69,28 @@ func TestNewAppointment(t *testing.T) {
 			},
 			want: fakeAppointmentWithoutSalonID,
 		},
+		{
+			name: "success, created appointment with duration, service and price",
+			args: args{
+				UpsertAppointment{
+					SalonID:         1,
+					ProfessionalID:  3,
+					AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+					DurationMinutes: 90,
+					ServiceType:     ServiceColoring,
+					PriceCents:      12000,
+				},
+			},
+			want: Appointment{
+				SalonID:         1,
+				ProfessionalID:  3,
+				AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+				EndDate:         time.Date(2022, 05, 12, 20, 0, 25, 12, time.Local),
+				ServiceType:     ServiceColoring,
+				PriceCents:      12000,
+				Status:          StatusAvailable,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -71,3 +99,21 @@ func TestNewAppointment(t *testing.T) {
 		})
 	}
 }
+
+func TestAppointment_End(t *testing.T) {
+	start := time.Date(2022, 05, 12, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		app  Appointment
+		want time.Time
+	}{
+		{name: "success, stored end date", app: Appointment{AppointmentDate: start, EndDate: start.Add(time.Hour)}, want: start.Add(time.Hour)},
+		{name: "success, legacy slot duration", app: Appointment{AppointmentDate: start}, want: start.Add(SlotDuration)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.app.End())
+			assert.Equal(t, tt.want.Sub(start), tt.app.Duration())
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// OutboxEntry is an event stored with the change that produced it, waiting
+// to be relayed to the broker. Key is the idempotency key consumers use to
+// drop the entries relayed more than once.
+type OutboxEntry struct {
+	ID        string     `bson:"_id,omitempty"`
+	Key       string     `bson:"key"`
+	Type      string     `bson:"type"`
+	Payload   []byte     `bson:"payload"`
+	CreatedAt time.Time  `bson:"created_at"`
+	SentAt    *time.Time `bson:"sent_at,omitempty"`
+	// ParkedAt is when the relay gave up on the entry, it is kept for
+	// inspection but no longer relayed.
+	ParkedAt  *time.Time `bson:"parked_at,omitempty"`
+	Attempts  int        `bson:"attempts"`
+	LastError string     `bson:"last_error,omitempty"`
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+
+	SortDateAsc  = "appointment_date"
+	SortDateDesc = "-appointment_date"
+)
+
+// ListOptions narrows and orders the appointments returned by list queries.
+// Page is the opaque token returned as NextPage by the previous page.
+type ListOptions struct {
+	Limit int       `json:"limit" validate:"omitempty,min=1,max=100"`
+	Page  string    `json:"page"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Sort  string    `json:"sort" validate:"omitempty,oneof=appointment_date -appointment_date"`
+}
+
+// IsDefault reports whether no option was given, that is the first page
+// of an unfiltered list in the default order.
+func (o ListOptions) IsDefault() bool {
+	return o.Limit == 0 && o.Page == "" && o.From.IsZero() && o.To.IsZero() && o.Sort == ""
+}
+
+// PageSize returns the number of appointments a page may hold.
+func (o ListOptions) PageSize() int {
+	if o.Limit <= 0 {
+		return DefaultPageSize
+	}
+
+	if o.Limit > MaxPageSize {
+		return MaxPageSize
+	}
+
+	return o.Limit
+}
+
+// Descending reports whether the list is ordered from the latest appointment.
+func (o ListOptions) Descending() bool {
+	return o.Sort == SortDateDesc
+}
+
+// AppointmentPage is one page of a list query.
+type AppointmentPage struct {
+	Appointments []Appointment `json:"appointments"`
+	NextPage     string        `json:"next_page"`
+	Total        int64         `json:"total"`
+}
+
+// PageCursor is the position after which the next page starts.
+type PageCursor struct {
+	Date time.Time `json:"d"`
+	ID   string    `json:"id"`
+}
+
+// EncodePageToken builds the token of the page that follows app.
+func EncodePageToken(app Appointment) string {
+	token, _ := json.Marshal(PageCursor{Date: app.AppointmentDate, ID: app.ID})
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// DecodePageToken parses a token built by EncodePageToken.
+func DecodePageToken(token string) (PageCursor, error) {
+	var cursor PageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, err
+	}
+
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, err
+	}
+
+	return cursor, nil
+}
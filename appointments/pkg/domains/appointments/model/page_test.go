@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListOptions_PageSize(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ListOptions
+		want int
+	}{
+		{name: "success, default size", opts: ListOptions{}, want: DefaultPageSize},
+		{name: "success, requested size", opts: ListOptions{Limit: 5}, want: 5},
+		{name: "success, capped size", opts: ListOptions{Limit: 500}, want: MaxPageSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.opts.PageSize())
+		})
+	}
+}
+
+func TestListOptions_IsDefault(t *testing.T) {
+	assert.True(t, ListOptions{}.IsDefault())
+	assert.False(t, ListOptions{Limit: 1}.IsDefault())
+	assert.False(t, ListOptions{Sort: SortDateDesc}.IsDefault())
+	assert.False(t, ListOptions{From: time.Now()}.IsDefault())
+}
+
+func TestPageToken(t *testing.T) {
+	app := Appointment{
+		ID:              "628ed8e442c5ab8d69b6d4fa",
+		AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.UTC),
+	}
+
+	cursor, err := DecodePageToken(EncodePageToken(app))
+	assert.NoError(t, err)
+	assert.Equal(t, PageCursor{Date: app.AppointmentDate, ID: app.ID}, cursor)
+
+	_, err = DecodePageToken("not-a-token")
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+// PatchAppointment is a JSON Merge Patch (RFC 7396) of an appointment. The
+// fields it holds replace the stored ones and fields set to null are
+// cleared, anything else is left as it is.
+type PatchAppointment struct {
+	ID string
+	// Version is the version the patch applies to.
+	Version int64
+	Fields  map[string]json.RawMessage
+}
+
+// Clears reports whether the patch sets field to null.
+func (p PatchAppointment) Clears(field string) bool {
+	value, ok := p.Fields[field]
+	return ok && string(value) == "null"
+}
+
+// Apply returns app with the patch merged in.
+func (p PatchAppointment) Apply(app UpsertAppointment) (UpsertAppointment, error) {
+	doc, err := json.Marshal(app)
+	if err != nil {
+		return app, err
+	}
+
+	merged := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(doc, &merged); err != nil {
+		return app, err
+	}
+
+	for field, value := range p.Fields {
+		if p.Clears(field) {
+			delete(merged, field)
+			continue
+		}
+		merged[field] = value
+	}
+
+	if doc, err = json.Marshal(merged); err != nil {
+		return app, err
+	}
+
+	var patched UpsertAppointment
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return app, err
+	}
+
+	patched.ID = p.ID
+	patched.Version = p.Version
+	return patched, nil
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchAppointment_Apply(t *testing.T) {
+	stored := UpsertAppointment{
+		ID:              "628ed8e442c5ab8d69b6d4fa",
+		UserID:          1,
+		SalonID:         1,
+		ProfessionalID:  3,
+		AppointmentDate: time.Date(2030, time.June, 23, 21, 0, 0, 0, time.UTC),
+		DurationMinutes: 45,
+		ServiceType:     ServiceHaircut,
+		PriceCents:      4500,
+		Version:         2,
+	}
+	tests := []struct {
+		name   string
+		fields string
+		want   func(app UpsertAppointment) UpsertAppointment
+	}{
+		{
+			name:   "success, reschedules only the date",
+			fields: `{"appointment_date": "2030-06-24T10:00:00Z"}`,
+			want: func(app UpsertAppointment) UpsertAppointment {
+				app.AppointmentDate = time.Date(2030, time.June, 24, 10, 0, 0, 0, time.UTC)
+				return app
+			},
+		},
+		{
+			name:   "success, changes only the salon",
+			fields: `{"salon_id": 2}`,
+			want: func(app UpsertAppointment) UpsertAppointment {
+				app.SalonID = 2
+				return app
+			},
+		},
+		{
+			name:   "success, null clears the field",
+			fields: `{"professional_id": null, "service_type": null}`,
+			want: func(app UpsertAppointment) UpsertAppointment {
+				app.ProfessionalID = 0
+				app.ServiceType = ""
+				return app
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := PatchAppointment{ID: stored.ID, Version: 5}
+			assert.NoError(t, json.Unmarshal([]byte(tt.fields), &patch.Fields))
+
+			got, err := patch.Apply(stored)
+			assert.NoError(t, err)
+
+			want := tt.want(stored)
+			want.Version = 5
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("fail, wrong type", func(t *testing.T) {
+		patch := PatchAppointment{Fields: map[string]json.RawMessage{"salon_id": json.RawMessage(`"two"`)}}
+		_, err := patch.Apply(stored)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,10 @@
+package model
+
+// ServiceType is the service an appointment is booked for.
+type ServiceType string
+
+const (
+	ServiceHaircut  ServiceType = "haircut"
+	ServiceManicure ServiceType = "manicure"
+	ServiceColoring ServiceType = "coloring"
+)
@@ -0,0 +1,40 @@
+package model
+
+// Status is the lifecycle state of an appointment slot.
+type Status string
+
+const (
+	StatusAvailable Status = "available"
+	StatusBooked    Status = "booked"
+	StatusConfirmed Status = "confirmed"
+	StatusCheckedIn Status = "checked_in"
+	StatusCompleted Status = "completed"
+	StatusCancelled Status = "cancelled"
+	StatusNoShow    Status = "no_show"
+)
+
+// transitions lists, for every status, the statuses it may move to.
+// A cancelled booking frees the slot, so it can be booked again.
+var transitions = map[Status][]Status{
+	StatusAvailable: {StatusBooked},
+	StatusBooked:    {StatusConfirmed, StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusConfirmed: {StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusCheckedIn: {StatusCompleted},
+	StatusCancelled: {StatusBooked},
+}
+
+// CanTransition reports whether an appointment in status s may move to next.
+func (s Status) CanTransition(next Status) bool {
+	for _, st := range transitions[s] {
+		if st == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bookable reports whether a slot in status s can be taken by a user.
+func (s Status) Bookable() bool {
+	return s.CanTransition(StatusBooked)
+}
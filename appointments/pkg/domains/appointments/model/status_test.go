@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus_CanTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		from Status
+		to   Status
+		want bool
+	}{
+		{name: "success, book available", from: StatusAvailable, to: StatusBooked, want: true},
+		{name: "success, confirm booked", from: StatusBooked, to: StatusConfirmed, want: true},
+		{name: "success, check in confirmed", from: StatusConfirmed, to: StatusCheckedIn, want: true},
+		{name: "success, complete checked in", from: StatusCheckedIn, to: StatusCompleted, want: true},
+		{name: "success, cancel confirmed", from: StatusConfirmed, to: StatusCancelled, want: true},
+		{name: "success, no show booked", from: StatusBooked, to: StatusNoShow, want: true},
+		{name: "success, book cancelled", from: StatusCancelled, to: StatusBooked, want: true},
+		{name: "fail, confirm available", from: StatusAvailable, to: StatusConfirmed},
+		{name: "fail, complete booked", from: StatusBooked, to: StatusCompleted},
+		{name: "fail, cancel checked in", from: StatusCheckedIn, to: StatusCancelled},
+		{name: "fail, reopen completed", from: StatusCompleted, to: StatusBooked},
+		{name: "fail, check in no show", from: StatusNoShow, to: StatusCheckedIn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.from.CanTransition(tt.to))
+		})
+	}
+}
+
+func TestAppointment_State(t *testing.T) {
+	tests := []struct {
+		name string
+		app  Appointment
+		want Status
+	}{
+		{name: "success, stored status", app: Appointment{UserID: 1, Status: StatusConfirmed}, want: StatusConfirmed},
+		{name: "success, legacy available", app: Appointment{}, want: StatusAvailable},
+		{name: "success, legacy booked", app: Appointment{UserID: 1}, want: StatusBooked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.app.State())
+		})
+	}
+}
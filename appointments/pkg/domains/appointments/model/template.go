@@ -0,0 +1,190 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxGeneratedSlots bounds how many slots a single template may produce.
+	MaxGeneratedSlots = 5000
+
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// SlotTemplate describes the weekly opening hours of a salon, or of one of
+// its professionals. Slots are generated for every day between From and To,
+// both inclusive.
+type SlotTemplate struct {
+	SalonID        int            `json:"salon_id" validate:"required" example:"1"`
+	ProfessionalID int            `json:"professional_id,omitempty" example:"3"`
+	TimeZone       string         `json:"time_zone" validate:"required" example:"America/Sao_Paulo"`
+	From           string         `json:"from" validate:"required" example:"2022-07-01"`
+	To             string         `json:"to" validate:"required" example:"2022-07-31"`
+	SlotMinutes    int            `json:"slot_minutes" validate:"required,min=1" example:"30"`
+	Days           []OpeningHours `json:"days" validate:"required,min=1,dive"`
+	ServiceType    ServiceType    `json:"service_type,omitempty" validate:"omitempty,oneof=haircut manicure coloring" example:"haircut"`
+	PriceCents     int64          `json:"price_cents,omitempty" validate:"min=0" example:"4500"`
+}
+
+// OpeningHours are the hours a salon is open on a weekday, in the template
+// time zone. Breaks are periods of the day where no slot may start or run.
+type OpeningHours struct {
+	Weekday string  `json:"weekday" validate:"required,oneof=sunday monday tuesday wednesday thursday friday saturday" example:"monday"`
+	Open    string  `json:"open" validate:"required" example:"09:00"`
+	Close   string  `json:"close" validate:"required" example:"18:00"`
+	Breaks  []Break `json:"breaks" validate:"dive"`
+}
+
+type Break struct {
+	Start string `json:"start" validate:"required" example:"12:00"`
+	End   string `json:"end" validate:"required" example:"13:00"`
+}
+
+type GenerateResponse struct {
+	Created int `json:"created" example:"120"`
+	Skipped int `json:"skipped" example:"0"`
+}
+
+// period is a time of day range expressed as offsets from midnight.
+type period struct {
+	start, end time.Duration
+}
+
+// at returns the time of day offset, read on the clocks of the time zone of
+// day. On the days the clocks change it is not offset after midnight.
+func at(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// Slots returns the available appointments described by the template,
+// ordered by date.
+func (t SlotTemplate) Slots() ([]Appointment, error) {
+	loc, err := time.LoadLocation(t.TimeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid time_zone")
+	}
+
+	from, err := time.ParseInLocation(dateLayout, t.From, loc)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid from")
+	}
+
+	to, err := time.ParseInLocation(dateLayout, t.To, loc)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid to")
+	}
+
+	if to.Before(from) {
+		return nil, errors.New("to must not be before from")
+	}
+
+	if t.SlotMinutes <= 0 {
+		return nil, errors.New("slot_minutes must be positive")
+	}
+
+	week := make(map[time.Weekday][]OpeningHours)
+	for _, day := range t.Days {
+		weekday, err := parseWeekday(day.Weekday)
+		if err != nil {
+			return nil, err
+		}
+		week[weekday] = append(week[weekday], day)
+	}
+
+	length := time.Duration(t.SlotMinutes) * time.Minute
+	slots := make([]Appointment, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, hours := range week[day.Weekday()] {
+			open, breaks, err := hours.periods()
+			if err != nil {
+				return nil, err
+			}
+
+			for start := open.start; start+length <= open.end; start += length {
+				slot := period{start: start, end: start + length}
+				if slot.overlapsAny(breaks) {
+					continue
+				}
+
+				if len(slots) == MaxGeneratedSlots {
+					return nil, fmt.Errorf("template generates more than %d slots", MaxGeneratedSlots)
+				}
+
+				slots = append(slots, Appointment{
+					SalonID:         t.SalonID,
+					ProfessionalID:  t.ProfessionalID,
+					AppointmentDate: at(day, slot.start).UTC(),
+					EndDate:         at(day, slot.end).UTC(),
+					ServiceType:     t.ServiceType,
+					PriceCents:      t.PriceCents,
+					Status:          StatusAvailable,
+				})
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+func (o OpeningHours) periods() (period, []period, error) {
+	open, err := parsePeriod(o.Open, o.Close)
+	if err != nil {
+		return period{}, nil, errors.Wrapf(err, "invalid opening hours on %s", o.Weekday)
+	}
+
+	breaks := make([]period, 0, len(o.Breaks))
+	for _, b := range o.Breaks {
+		p, err := parsePeriod(b.Start, b.End)
+		if err != nil {
+			return period{}, nil, errors.Wrapf(err, "invalid break on %s", o.Weekday)
+		}
+		breaks = append(breaks, p)
+	}
+
+	return open, breaks, nil
+}
+
+func (p period) overlapsAny(others []period) bool {
+	for _, o := range others {
+		if p.start < o.end && o.start < p.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parsePeriod(start, end string) (period, error) {
+	s, err := time.Parse(clockLayout, start)
+	if err != nil {
+		return period{}, err
+	}
+
+	e, err := time.Parse(clockLayout, end)
+	if err != nil {
+		return period{}, err
+	}
+
+	if !e.After(s) {
+		return period{}, errors.Errorf("%s must be after %s", end, start)
+	}
+
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return period{start: s.Sub(midnight), end: e.Sub(midnight)}, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, nil
+		}
+	}
+
+	return 0, errors.Errorf("invalid weekday %q", name)
+}
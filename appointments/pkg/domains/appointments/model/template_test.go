@@ -0,0 +1,134 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotTemplate_Slots(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	require.NoError(t, err)
+	slot := func(day, hour, min int, length time.Duration) Appointment {
+		start := time.Date(2022, time.July, day, hour, min, 0, 0, saoPaulo)
+		return Appointment{
+			SalonID:         1,
+			AppointmentDate: start.UTC(),
+			EndDate:         start.Add(length).UTC(),
+			Status:          StatusAvailable,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		template SlotTemplate
+		want     []Appointment
+		wantErr  bool
+	}{
+		{
+			name: "success, slots around a break",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "America/Sao_Paulo", From: "2022-07-04", To: "2022-07-04", SlotMinutes: 30,
+				Days: []OpeningHours{{
+					Weekday: "monday", Open: "09:00", Close: "11:00",
+					Breaks: []Break{{Start: "09:45", End: "10:15"}},
+				}},
+			},
+			want: []Appointment{slot(4, 9, 0, 30*time.Minute), slot(4, 10, 30, 30*time.Minute)},
+		},
+		{
+			name: "success, only matching weekdays",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "America/Sao_Paulo", From: "2022-07-01", To: "2022-07-10", SlotMinutes: 60,
+				Days: []OpeningHours{{Weekday: "Saturday", Open: "09:00", Close: "10:30"}},
+			},
+			want: []Appointment{slot(2, 9, 0, time.Hour), slot(9, 9, 0, time.Hour)},
+		},
+		{
+			name: "success, no opening day in range",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "UTC", From: "2022-07-04", To: "2022-07-04", SlotMinutes: 30,
+				Days: []OpeningHours{{Weekday: "sunday", Open: "09:00", Close: "10:00"}},
+			},
+			want: []Appointment{},
+		},
+		{
+			name: "fail, unknown time zone",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "Mars/Olympus", From: "2022-07-04", To: "2022-07-04", SlotMinutes: 30,
+				Days: []OpeningHours{{Weekday: "monday", Open: "09:00", Close: "10:00"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail, range ends before it starts",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "UTC", From: "2022-07-04", To: "2022-07-01", SlotMinutes: 30,
+				Days: []OpeningHours{{Weekday: "monday", Open: "09:00", Close: "10:00"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail, closes before it opens",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "UTC", From: "2022-07-04", To: "2022-07-04", SlotMinutes: 30,
+				Days: []OpeningHours{{Weekday: "monday", Open: "18:00", Close: "09:00"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail, too many slots",
+			template: SlotTemplate{
+				SalonID: 1, TimeZone: "UTC", From: "2022-01-01", To: "2022-12-31", SlotMinutes: 1,
+				Days: []OpeningHours{{Weekday: "monday", Open: "00:00", Close: "23:59"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.template.Slots()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSlotTemplate_SlotsAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// The clocks of New York go forward on 2022-03-13 and back on 2022-11-06.
+	for _, weekend := range []string{"2022-03-12", "2022-11-05"} {
+		t.Run(weekend, func(t *testing.T) {
+			template := SlotTemplate{
+				SalonID: 1, TimeZone: "America/New_York", SlotMinutes: 60,
+				Days: []OpeningHours{
+					{Weekday: "saturday", Open: "09:00", Close: "10:00"},
+					{Weekday: "sunday", Open: "09:00", Close: "10:00"},
+				},
+			}
+			saturday, err := time.ParseInLocation(dateLayout, weekend, newYork)
+			require.NoError(t, err)
+			template.From = weekend
+			template.To = saturday.AddDate(0, 0, 1).Format(dateLayout)
+
+			got, err := template.Slots()
+			require.NoError(t, err)
+			require.Len(t, got, 2)
+			for i, slot := range got {
+				start, end := slot.AppointmentDate.In(newYork), slot.EndDate.In(newYork)
+				assert.Equal(t, saturday.AddDate(0, 0, i).Day(), start.Day())
+				assert.Equal(t, 9, start.Hour(), "opens at 09:00 on both days")
+				assert.Equal(t, 10, end.Hour())
+				assert.Equal(t, time.Hour, end.Sub(start))
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/log"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	userID         = "user_"
+	salonID        = "salon_"
+	professionalID = "professional_"
+)
+
+// The queries CachedRepository caches, as reported in the metrics.
+const (
+	queryByID           = "by_id"
+	queryByUser         = "by_user"
+	queryBySalon        = "by_salon"
+	queryByProfessional = "by_professional"
+)
+
+var (
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appointment_cache_hits_total",
+		Help: "Appointment queries answered by the cache.",
+	}, []string{"query"})
+	cacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appointment_cache_misses_total",
+		Help: "Appointment queries read from the repository behind the cache.",
+	}, []string{"query"})
+)
+
+// CacheTTL is how long each query stays cached, a query whose TTL is not
+// positive is not cached. NotFound is how long an id found missing is
+// remembered as missing.
+type CacheTTL struct {
+	ByID           time.Duration
+	ByUser         time.Duration
+	BySalon        time.Duration
+	ByProfessional time.Duration
+	NotFound       time.Duration
+}
+
+// CachedRepository caches the appointments read by id and the first page of
+// the default listing of a user, salon or professional, reading everything
+// else straight from the wrapped repository. Concurrent misses of the same
+// entry are read from the repository once.
+//
+// Every write evicts the entries of the appointment it changes, including
+// the listings it leaves. Writes made in a transaction evict once the
+// transaction ends, and reads made in one skip the cache, so they see the
+// writes made before them. An entry loaded before an eviction of its key by
+// this repository is not left cached after it, however the two interleave.
+// Evictions made by another process sharing the cache do not stop the loads
+// of this one, so an entry loaded before such a write can stay cached until
+// its TTL runs out.
+type CachedRepository struct {
+	next  AppointmentRepositoryI
+	cache CacheI
+	ttl   CacheTTL
+	log   log.AppointmentLogI
+	group singleflight.Group
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is the load of an entry in progress, evicted once its key is
+// evicted while it loads.
+type flight struct {
+	evicted bool
+}
+
+func NewCachedRepository(next AppointmentRepositoryI, cache CacheI, ttl CacheTTL,
+	l log.AppointmentLogI) *CachedRepository {
+	return &CachedRepository{
+		next:  next,
+		cache: cache,
+		ttl:   ttl,
+		log:   l,
+
+		flights: make(map[string]*flight),
+	}
+}
+
+// cacheTxKey holds the keys to evict once the transaction of the context
+// ends.
+type cacheTxKey struct{}
+
+type cacheTx struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func inCacheTx(ctx context.Context) (*cacheTx, bool) {
+	tx, ok := ctx.Value(cacheTxKey{}).(*cacheTx)
+	return tx, ok
+}
+
+// WithTransaction evicts the entries written by fn once the transaction
+// ends, whether it commits or not.
+func (c *CachedRepository) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	if _, ok := inCacheTx(ctx); ok {
+		return c.next.WithTransaction(ctx, fn)
+	}
+
+	tx := &cacheTx{}
+	err := c.next.WithTransaction(context.WithValue(ctx, cacheTxKey{}, tx), fn)
+	c.delete(tx.keys...)
+
+	return err
+}
+
+func (c *CachedRepository) CreateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
+	created, err := c.next.CreateAppointment(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+
+	c.evict(ctx, *created)
+	return created, nil
+}
+
+func (c *CachedRepository) CreateAppointments(ctx context.Context, apps []model.Appointment) ([]model.Appointment, error) {
+	created, err := c.next.CreateAppointments(ctx, apps)
+	if err != nil {
+		return nil, err
+	}
+
+	c.evict(ctx, created...)
+	return created, nil
+}
+
+func (c *CachedRepository) UpdateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
+	return c.write(ctx, app.ID, func() (*model.Appointment, error) {
+		return c.next.UpdateAppointment(ctx, app)
+	})
+}
+
+func (c *CachedRepository) DeleteAppointment(ctx context.Context, id string, version int64) error {
+	_, err := c.write(ctx, id, func() (*model.Appointment, error) {
+		return nil, c.next.DeleteAppointment(ctx, id, version)
+	})
+
+	return err
+}
+
+func (c *CachedRepository) MakeAppointment(ctx context.Context, id string, user int) (*model.Appointment, error) {
+	return c.write(ctx, id, func() (*model.Appointment, error) {
+		return c.next.MakeAppointment(ctx, id, user)
+	})
+}
+
+func (c *CachedRepository) CancelAppointment(ctx context.Context, id string, user int, version int64) error {
+	_, err := c.write(ctx, id, func() (*model.Appointment, error) {
+		return nil, c.next.CancelAppointment(ctx, id, user, version)
+	})
+
+	return err
+}
+
+func (c *CachedRepository) UpdateStatus(ctx context.Context, id string, from, to model.Status) (*model.Appointment, error) {
+	app, err := c.next.UpdateStatus(ctx, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	c.evict(ctx, *app)
+	return app, nil
+}
+
+// write runs fn, a write of the appointment id, and evicts the entries of
+// the appointment before and after it. The appointment is read first, the
+// write may take it off the listings it is in.
+func (c *CachedRepository) write(ctx context.Context, id string,
+	fn func() (*model.Appointment, error)) (*model.Appointment, error) {
+	old, err := c.next.FindAppointmentByID(ctx, id)
+	if err != nil {
+		old = &model.Appointment{ID: id}
+	}
+
+	app, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	if app != nil {
+		c.evict(ctx, *old, *app)
+	} else {
+		c.evict(ctx, *old)
+	}
+
+	return app, nil
+}
+
+func (c *CachedRepository) FindAllAppointments(ctx context.Context, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return c.next.FindAllAppointments(ctx, opts)
+}
+
+func (c *CachedRepository) AvaiableAppointment(ctx context.Context, find model.FindAvailable) (*model.AppointmentPage, error) {
+	return c.next.AvaiableAppointment(ctx, find)
+}
+
+func (c *CachedRepository) HasOverlap(ctx context.Context, app model.Appointment) (bool, error) {
+	return c.next.HasOverlap(ctx, app)
+}
+
+// FindAppointmentByID also caches the ids found missing, for CacheTTL.NotFound.
+func (c *CachedRepository) FindAppointmentByID(ctx context.Context, id string) (*model.Appointment, error) {
+	if _, ok := inCacheTx(ctx); ok || c.ttl.ByID <= 0 {
+		return c.next.FindAppointmentByID(ctx, id)
+	}
+
+	var app model.Appointment
+	err := c.read(ctx, queryByID, id, c.ttl.ByID, &app, func(ctx context.Context) (interface{}, error) {
+		return c.next.FindAppointmentByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
+
+func (c *CachedRepository) FindAppointmentByUserID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return c.findPage(ctx, queryByUser, fmt.Sprintf("%v%d", userID, id), c.ttl.ByUser, opts,
+		func(ctx context.Context) (*model.AppointmentPage, error) {
+			return c.next.FindAppointmentByUserID(ctx, id, opts)
+		})
+}
+
+func (c *CachedRepository) FindAppointmentBySalonID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return c.findPage(ctx, queryBySalon, fmt.Sprintf("%v%d", salonID, id), c.ttl.BySalon, opts,
+		func(ctx context.Context) (*model.AppointmentPage, error) {
+			return c.next.FindAppointmentBySalonID(ctx, id, opts)
+		})
+}
+
+func (c *CachedRepository) FindAppointmentByProfessionalID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return c.findPage(ctx, queryByProfessional, fmt.Sprintf("%v%d", professionalID, id), c.ttl.ByProfessional, opts,
+		func(ctx context.Context) (*model.AppointmentPage, error) {
+			return c.next.FindAppointmentByProfessionalID(ctx, id, opts)
+		})
+}
+
+// findPage only caches the first page of the default listing, any other page
+// or filter is read straight from the repository.
+func (c *CachedRepository) findPage(ctx context.Context, query, key string, ttl time.Duration,
+	opts model.ListOptions, load func(context.Context) (*model.AppointmentPage, error)) (*model.AppointmentPage, error) {
+	if _, ok := inCacheTx(ctx); ok || ttl <= 0 || !opts.IsDefault() {
+		return load(ctx)
+	}
+
+	var page model.AppointmentPage
+	err := c.read(ctx, query, key, ttl, &page, func(ctx context.Context) (interface{}, error) {
+		return load(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// read decodes the entry of key into value. On a miss the entry is loaded
+// once for all the concurrent readers of key, and cached for ttl. An
+// ErrNotFound from load is cached too, as an empty entry.
+//
+// The load goes on when the reader that started it gives up, the other
+// readers of key may still be waiting for it.
+func (c *CachedRepository) read(ctx context.Context, query, key string, ttl time.Duration, value interface{},
+	load func(context.Context) (interface{}, error)) error {
+	entry, err := c.cache.Get(key)
+	if err == nil {
+		cacheHits.WithLabelValues(query).Inc()
+		if len(entry) == 0 {
+			return appErr.ErrNotFound
+		}
+
+		return json.Unmarshal(entry, value)
+	}
+
+	cacheMisses.WithLabelValues(query).Inc()
+	if !errors.Is(err, appErr.ErrNotFound) {
+		_ = c.log.LogWithTime(err)
+	}
+
+	loaded := c.group.DoChan(key, func() (interface{}, error) {
+		return c.load(detached{ctx}, key, ttl, load)
+	})
+
+	select {
+	case result := <-loaded:
+		if result.Err != nil {
+			return result.Err
+		}
+
+		// Every reader decodes its own copy of the shared entry.
+		return json.Unmarshal(result.Val.([]byte), value)
+	case <-ctx.Done():
+		return errors.Wrap(appErr.ErrDatabase, ctx.Err().Error())
+	}
+}
+
+// load reads the entry of key from the repository and caches it, unless key
+// is evicted meanwhile: the entry may have been read before the write that
+// evicted it.
+func (c *CachedRepository) load(ctx context.Context, key string, ttl time.Duration,
+	load func(context.Context) (interface{}, error)) ([]byte, error) {
+	f := &flight{}
+	c.mu.Lock()
+	c.flights[key] = f
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+	}()
+
+	loaded, err := load(ctx)
+	if errors.Is(err, appErr.ErrNotFound) && c.ttl.NotFound > 0 {
+		c.fill(f, key, []byte{}, c.ttl.NotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(loaded)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
+	}
+
+	c.fill(f, key, encoded, ttl)
+	return encoded, nil
+}
+
+// fill caches the entry loaded by f. An eviction of key that lands while
+// the entry is being set is seen right after, and the entry is deleted
+// again.
+func (c *CachedRepository) fill(f *flight, key string, value []byte, ttl time.Duration) {
+	if c.evicted(f) {
+		return
+	}
+
+	c.set(key, value, ttl)
+	if c.evicted(f) {
+		c.delete(key)
+	}
+}
+
+func (c *CachedRepository) evicted(f *flight) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return f.evicted
+}
+
+func (c *CachedRepository) set(key string, value []byte, ttl time.Duration) {
+	if err := c.cache.Set(key, value, ttl); err != nil {
+		_ = c.log.LogWithTime(err)
+	}
+}
+
+// evict deletes the entries of apps, or leaves them to the transaction of
+// ctx to delete once it ends.
+func (c *CachedRepository) evict(ctx context.Context, apps ...model.Appointment) {
+	var keys []string
+	for _, app := range apps {
+		if app.ID != "" {
+			keys = append(keys, app.ID)
+		}
+		// The listings of id 0, such as the slots nobody booked, are cached
+		// like any other.
+		keys = append(keys,
+			fmt.Sprintf("%v%d", userID, app.UserID),
+			fmt.Sprintf("%v%d", salonID, app.SalonID),
+			fmt.Sprintf("%v%d", professionalID, app.ProfessionalID))
+	}
+
+	if tx, ok := inCacheTx(ctx); ok {
+		tx.mu.Lock()
+		tx.keys = append(tx.keys, keys...)
+		tx.mu.Unlock()
+		return
+	}
+
+	c.delete(keys...)
+}
+
+// delete deletes the entries of keys, and the entries of keys still
+// loading once they load.
+func (c *CachedRepository) delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	for _, key := range keys {
+		if f, ok := c.flights[key]; ok {
+			f.evicted = true
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.cache.Delete(keys...); err != nil {
+		_ = c.log.LogWithTime(err)
+	}
+}
+
+// detached keeps the values of a context but not its cancellation, for the
+// loads shared by several readers.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
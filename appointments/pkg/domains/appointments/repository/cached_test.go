@@ -0,0 +1,430 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/log"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCacheTTL = CacheTTL{
+	ByID:           time.Hour,
+	ByUser:         time.Minute,
+	BySalon:        time.Minute,
+	ByProfessional: time.Minute,
+	NotFound:       time.Second,
+}
+
+func newTestCached(t *testing.T, ttl CacheTTL) (*CachedRepository, *MockAppointmentRepositoryI, *InMemoryCache) {
+	t.Helper()
+	next := NewMockAppointmentRepositoryI(gomock.NewController(t))
+	cache := NewInMemoryCache()
+	return NewCachedRepository(next, cache, ttl, log.NewStdLog()), next, cache
+}
+
+func TestCachedRepository_FindAppointmentByID(t *testing.T) {
+	ctx := context.Background()
+	app := model.Appointment{ID: "629aac9c363519d9a9615369", UserID: 1, SalonID: 2, Version: 3}
+
+	t.Run("hit", func(t *testing.T) {
+		cached, next, _ := newTestCached(t, testCacheTTL)
+		next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).Return(&app, nil).Times(1)
+		hits := testutil.ToFloat64(cacheHits.WithLabelValues(queryByID))
+		misses := testutil.ToFloat64(cacheMisses.WithLabelValues(queryByID))
+
+		for i := 0; i < 3; i++ {
+			got, err := cached.FindAppointmentByID(ctx, app.ID)
+			require.NoError(t, err)
+			assert.Equal(t, app, *got)
+		}
+
+		assert.Equal(t, hits+2, testutil.ToFloat64(cacheHits.WithLabelValues(queryByID)))
+		assert.Equal(t, misses+1, testutil.ToFloat64(cacheMisses.WithLabelValues(queryByID)))
+	})
+
+	t.Run("not found is cached", func(t *testing.T) {
+		cached, next, cache := newTestCached(t, testCacheTTL)
+		now := time.Date(2030, time.June, 23, 9, 0, 0, 0, time.UTC)
+		cache.now = func() time.Time { return now }
+		next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).Return(nil, appErr.ErrNotFound).Times(2)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.FindAppointmentByID(ctx, app.ID)
+			assert.ErrorIs(t, err, appErr.ErrNotFound)
+		}
+
+		now = now.Add(testCacheTTL.NotFound)
+		_, err := cached.FindAppointmentByID(ctx, app.ID)
+		assert.ErrorIs(t, err, appErr.ErrNotFound)
+	})
+
+	t.Run("not found is not cached without ttl", func(t *testing.T) {
+		ttl := testCacheTTL
+		ttl.NotFound = 0
+		cached, next, _ := newTestCached(t, ttl)
+		next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).Return(nil, appErr.ErrNotFound).Times(2)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.FindAppointmentByID(ctx, app.ID)
+			assert.ErrorIs(t, err, appErr.ErrNotFound)
+		}
+	})
+
+	t.Run("other errors are not cached", func(t *testing.T) {
+		cached, next, _ := newTestCached(t, testCacheTTL)
+		next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).Return(nil, appErr.ErrDatabase)
+		next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).Return(&app, nil)
+
+		_, err := cached.FindAppointmentByID(ctx, app.ID)
+		assert.ErrorIs(t, err, appErr.ErrDatabase)
+		got, err := cached.FindAppointmentByID(ctx, app.ID)
+		require.NoError(t, err)
+		assert.Equal(t, app, *got)
+	})
+
+	t.Run("cache failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		next := NewMockAppointmentRepositoryI(ctrl)
+		cache := NewMockCacheI(ctrl)
+		l := log.NewMockAppointmentLogI(ctrl)
+		cached := NewCachedRepository(next, cache, testCacheTTL, l)
+
+		unavailable := errors.New("connection refused")
+		cache.EXPECT().Get(app.ID).Return(nil, unavailable)
+		next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).Return(&app, nil)
+		cache.EXPECT().Set(app.ID, gomock.Any(), testCacheTTL.ByID).Return(unavailable)
+		l.EXPECT().LogWithTime(unavailable).Times(2)
+
+		got, err := cached.FindAppointmentByID(ctx, app.ID)
+		require.NoError(t, err)
+		assert.Equal(t, app, *got)
+	})
+}
+
+func TestCachedRepository_FindAppointmentByUserID(t *testing.T) {
+	ctx := context.Background()
+	cached, next, cache := newTestCached(t, testCacheTTL)
+	now := time.Date(2030, time.June, 23, 9, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	page := model.AppointmentPage{Appointments: []model.Appointment{{ID: "629aac9c363519d9a9615369", UserID: 1}}, Total: 1}
+	next.EXPECT().FindAppointmentByUserID(gomock.Any(), 1, model.ListOptions{}).Return(&page, nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		got, err := cached.FindAppointmentByUserID(ctx, 1, model.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, page, *got)
+	}
+
+	now = now.Add(testCacheTTL.ByUser)
+	_, err := cached.FindAppointmentByUserID(ctx, 1, model.ListOptions{})
+	require.NoError(t, err)
+
+	opts := model.ListOptions{Limit: 5}
+	next.EXPECT().FindAppointmentByUserID(gomock.Any(), 1, opts).Return(&page, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := cached.FindAppointmentByUserID(ctx, 1, opts)
+		require.NoError(t, err, "only the default listing is cached")
+	}
+}
+
+func TestCachedRepository_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	cached, next, _ := newTestCached(t, testCacheTTL)
+	app := model.Appointment{ID: "629aac9c363519d9a9615369", SalonID: 2}
+
+	release := make(chan struct{})
+	next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).DoAndReturn(
+		func(context.Context, string) (*model.Appointment, error) {
+			<-release
+			return &app, nil
+		}).Times(1)
+
+	const readers = 10
+	var wg sync.WaitGroup
+	got := make([]*model.Appointment, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], _ = cached.FindAppointmentByID(ctx, app.ID)
+		}(i)
+	}
+
+	// Give every reader the time to miss before the first one loads.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := range got {
+		require.NotNil(t, got[i])
+		assert.Equal(t, app, *got[i])
+	}
+	got[0].UserID = 7
+	assert.Zero(t, got[1].UserID, "every reader gets its own copy")
+}
+
+// TestCachedRepository_StaleLoad writes an appointment while a read of it is
+// loading the version before the write.
+func TestCachedRepository_StaleLoad(t *testing.T) {
+	ctx := context.Background()
+	old := model.Appointment{ID: "629aac9c363519d9a9615369", SalonID: 2, Version: 1}
+	booked := old
+	booked.UserID, booked.Version = 7, 2
+
+	tests := []struct {
+		name string
+		// onSet writes as the stale entry is being set rather than while
+		// it is read from the repository.
+		onSet bool
+	}{
+		{name: "write while the entry loads"},
+		{name: "write while the entry is set", onSet: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := NewMockAppointmentRepositoryI(gomock.NewController(t))
+			hook := &setHook{InMemoryCache: NewInMemoryCache()}
+			cached := NewCachedRepository(next, hook, testCacheTTL, log.NewStdLog())
+			write := func() {
+				next.EXPECT().FindAppointmentByID(ctx, old.ID).Return(&old, nil)
+				next.EXPECT().MakeAppointment(ctx, old.ID, 7).Return(&booked, nil)
+				_, err := cached.MakeAppointment(ctx, old.ID, 7)
+				require.NoError(t, err)
+			}
+
+			next.EXPECT().FindAppointmentByID(gomock.Any(), old.ID).DoAndReturn(
+				func(context.Context, string) (*model.Appointment, error) {
+					if tt.onSet {
+						hook.onSet = write
+					} else {
+						write()
+					}
+					return &old, nil
+				})
+			got, err := cached.FindAppointmentByID(ctx, old.ID)
+			require.NoError(t, err)
+			assert.Equal(t, old, *got, "the read returns what it loaded")
+
+			next.EXPECT().FindAppointmentByID(gomock.Any(), old.ID).Return(&booked, nil)
+			got, err = cached.FindAppointmentByID(ctx, old.ID)
+			require.NoError(t, err)
+			assert.Equal(t, booked, *got, "the stale entry is not cached")
+		})
+	}
+}
+
+// setHook runs onSet, once, right before an entry is set.
+type setHook struct {
+	*InMemoryCache
+	onSet func()
+}
+
+func (c *setHook) Set(key string, value []byte, ttl time.Duration) error {
+	if onSet := c.onSet; onSet != nil {
+		c.onSet = nil
+		onSet()
+	}
+	return c.InMemoryCache.Set(key, value, ttl)
+}
+
+func TestCachedRepository_CancelledReader(t *testing.T) {
+	cached, next, _ := newTestCached(t, testCacheTTL)
+	app := model.Appointment{ID: "629aac9c363519d9a9615369", SalonID: 2}
+
+	loading, release := make(chan struct{}), make(chan struct{})
+	next.EXPECT().FindAppointmentByID(gomock.Any(), app.ID).DoAndReturn(
+		func(ctx context.Context, _ string) (*model.Appointment, error) {
+			close(loading)
+			<-release
+			return &app, ctx.Err()
+		}).Times(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := cached.FindAppointmentByID(ctx, app.ID)
+		first <- err
+	}()
+	<-loading
+
+	second := make(chan *model.Appointment)
+	go func() {
+		got, err := cached.FindAppointmentByID(context.Background(), app.ID)
+		assert.NoError(t, err)
+		second <- got
+	}()
+	// Give the second reader the time to wait for the first one's load.
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, appErr.ErrDatabase, "the reader that gave up returns at once")
+
+	close(release)
+	got := <-second
+	require.NotNil(t, got)
+	assert.Equal(t, app, *got, "the load goes on for the other readers")
+}
+
+func TestCachedRepository_Evict(t *testing.T) {
+	ctx := context.Background()
+	old := model.Appointment{ID: "629aac9c363519d9a9615369", UserID: 1, SalonID: 2, ProfessionalID: 3, Version: 1}
+	moved := old
+	moved.UserID, moved.SalonID, moved.ProfessionalID, moved.Version = 4, 5, 6, 2
+	free := model.Appointment{ID: "62b65300e1d7eab1ea9a681d", SalonID: 2, Version: 1}
+	all := []string{old.ID, "user_1", "salon_2", "professional_3", "user_4", "salon_5", "professional_6",
+		free.ID, "user_0", "professional_0"}
+	unfree := []string{free.ID, "user_0", "professional_0"}
+
+	tests := []struct {
+		name  string
+		write func(*CachedRepository, *MockAppointmentRepositoryI) error
+		kept  []string
+	}{
+		{
+			name: "update",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().FindAppointmentByID(ctx, old.ID).Return(&old, nil)
+				next.EXPECT().UpdateAppointment(ctx, moved).Return(&moved, nil)
+				_, err := c.UpdateAppointment(ctx, moved)
+				return err
+			},
+			kept: unfree,
+		},
+		{
+			name: "make",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().FindAppointmentByID(ctx, old.ID).Return(&old, nil)
+				next.EXPECT().MakeAppointment(ctx, old.ID, 4).Return(&moved, nil)
+				_, err := c.MakeAppointment(ctx, old.ID, 4)
+				return err
+			},
+			kept: unfree,
+		},
+		{
+			name: "delete",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().FindAppointmentByID(ctx, old.ID).Return(&old, nil)
+				next.EXPECT().DeleteAppointment(ctx, old.ID, int64(1)).Return(nil)
+				return c.DeleteAppointment(ctx, old.ID, 1)
+			},
+			kept: append([]string{"user_4", "salon_5", "professional_6"}, unfree...),
+		},
+		{
+			name: "cancel",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().FindAppointmentByID(ctx, old.ID).Return(&old, nil)
+				next.EXPECT().CancelAppointment(ctx, old.ID, 1, old.Version).Return(nil)
+				return c.CancelAppointment(ctx, old.ID, 1, old.Version)
+			},
+			kept: append([]string{"user_4", "salon_5", "professional_6"}, unfree...),
+		},
+		{
+			name: "update status",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().UpdateStatus(ctx, old.ID, model.StatusBooked, model.StatusConfirmed).Return(&moved, nil)
+				_, err := c.UpdateStatus(ctx, old.ID, model.StatusBooked, model.StatusConfirmed)
+				return err
+			},
+			kept: append([]string{"user_1", "salon_2", "professional_3"}, unfree...),
+		},
+		{
+			name: "create",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().CreateAppointments(ctx, []model.Appointment{old}).Return([]model.Appointment{old}, nil)
+				_, err := c.CreateAppointments(ctx, []model.Appointment{old})
+				return err
+			},
+			kept: append([]string{"user_4", "salon_5", "professional_6"}, unfree...),
+		},
+		{
+			name: "create unbooked",
+			write: func(c *CachedRepository, next *MockAppointmentRepositoryI) error {
+				next.EXPECT().CreateAppointment(ctx, free).Return(&free, nil)
+				_, err := c.CreateAppointment(ctx, free)
+				return err
+			},
+			kept: []string{old.ID, "user_1", "professional_3", "user_4", "salon_5", "professional_6"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached, next, cache := newTestCached(t, testCacheTTL)
+			for _, key := range all {
+				require.NoError(t, cache.Set(key, []byte(`{}`), time.Hour))
+			}
+
+			require.NoError(t, tt.write(cached, next))
+
+			for _, key := range all {
+				_, err := cache.Get(key)
+				kept := false
+				for _, k := range tt.kept {
+					kept = kept || k == key
+				}
+				if kept {
+					assert.NoError(t, err, "%s is kept", key)
+				} else {
+					assert.ErrorIs(t, err, appErr.ErrNotFound, "%s is evicted", key)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedRepository_EvictFailure(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	next := NewMockAppointmentRepositoryI(ctrl)
+	cache := NewMockCacheI(ctrl)
+	l := log.NewMockAppointmentLogI(ctrl)
+	cached := NewCachedRepository(next, cache, testCacheTTL, l)
+
+	app := model.Appointment{ID: "629aac9c363519d9a9615369", UserID: 1, SalonID: 2}
+	next.EXPECT().UpdateStatus(ctx, app.ID, model.StatusBooked, model.StatusConfirmed).Return(&app, nil)
+	cache.EXPECT().Delete(app.ID, "user_1", "salon_2", "professional_0").Return(appErr.ErrMemoryDatabase)
+	l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase)
+
+	got, err := cached.UpdateStatus(ctx, app.ID, model.StatusBooked, model.StatusConfirmed)
+	require.NoError(t, err, "the write is done even when the eviction fails")
+	assert.Equal(t, app, *got)
+}
+
+func TestCachedRepository_WithTransaction(t *testing.T) {
+	ctx := context.Background()
+	cached := NewCachedRepository(NewInMemoryRepository(), NewInMemoryCache(), testCacheTTL, log.NewStdLog())
+	app, err := cached.CreateAppointment(ctx, model.Appointment{SalonID: 2, Status: model.StatusAvailable})
+	require.NoError(t, err)
+	_, err = cached.FindAppointmentByID(ctx, app.ID)
+	require.NoError(t, err)
+
+	fail := errors.New("rollback")
+	err = cached.WithTransaction(ctx, func(ctx context.Context) error {
+		booked, err := cached.MakeAppointment(ctx, app.ID, 7)
+		require.NoError(t, err)
+
+		got, err := cached.FindAppointmentByID(ctx, app.ID)
+		require.NoError(t, err)
+		assert.Equal(t, booked.UserID, got.UserID, "reads in the transaction skip the cache")
+
+		outside, err := cached.FindAppointmentByID(context.Background(), app.ID)
+		require.NoError(t, err)
+		assert.Zero(t, outside.UserID, "the entry stays until the transaction ends")
+		return fail
+	})
+	require.ErrorIs(t, err, fail)
+
+	got, err := cached.FindAppointmentByID(ctx, app.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.UserID)
+	assert.Equal(t, app.Version, got.Version)
+}
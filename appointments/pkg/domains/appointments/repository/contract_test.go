@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/log"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository/repositorytest"
+)
+
+func TestInMemoryRepository_Contract(t *testing.T) {
+	repositorytest.TestAppointmentRepository(t, func(t *testing.T) repository.AppointmentRepositoryI {
+		return repository.NewInMemoryRepository()
+	})
+}
+
+func TestMongoRepository_Contract(t *testing.T) {
+	repositorytest.TestAppointmentRepository(t, func(t *testing.T) repository.AppointmentRepositoryI {
+		return repository.NewTestMongo(t)
+	})
+}
+
+func TestPostgresRepository_Contract(t *testing.T) {
+	repositorytest.TestAppointmentRepository(t, func(t *testing.T) repository.AppointmentRepositoryI {
+		return repository.NewTestPostgres(t)
+	})
+}
+
+func TestCachedRepository_Contract(t *testing.T) {
+	repositorytest.TestAppointmentRepository(t, func(t *testing.T) repository.AppointmentRepositoryI {
+		return repository.NewCachedRepository(repository.NewInMemoryRepository(),
+			repository.NewInMemoryCache(), repository.CacheTTL{
+				ByID:           time.Hour,
+				ByUser:         time.Hour,
+				BySalon:        time.Hour,
+				ByProfessional: time.Hour,
+				NotFound:       time.Hour,
+			}, log.NewStdLog())
+	})
+}
+
+func TestInMemoryCache_Contract(t *testing.T) {
+	repositorytest.TestCache(t, func(t *testing.T) repository.CacheI {
+		return repository.NewInMemoryCache()
+	})
+}
+
+func TestRedisCache_Contract(t *testing.T) {
+	repositorytest.TestCache(t, func(t *testing.T) repository.CacheI {
+		return repository.NewTestRedis(t)
+	})
+}
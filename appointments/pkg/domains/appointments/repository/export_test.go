@@ -0,0 +1,9 @@
+package repository
+
+// The contract tests in package repository_test set up the backends with
+// the helpers of the integration tests.
+var (
+	NewTestMongo    = newTestMongo
+	NewTestPostgres = newTestPostgres
+	NewTestRedis    = newTestRedis
+)
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	idempotencyKey = "idempotency_"
+	// reservation bounds how long a key stays claimed by a request that
+	// neither completes nor releases it, e.g. when the process crashes.
+	reservation = time.Minute
+	// IdempotencyTTL is how long the response of a request is kept.
+	IdempotencyTTL = 24 * time.Hour
+)
+
+// RedisIdempotency stores the responses in Redis, each after the
+// fingerprint of its request and a newline. A claimed key holds only the
+// fingerprint until the request completes, responses are never empty.
+type RedisIdempotency struct {
+	client *redis.Client
+}
+
+func NewRedisIdempotency(c *redis.Client) *RedisIdempotency {
+	return &RedisIdempotency{
+		client: c,
+	}
+}
+
+func (r *RedisIdempotency) ReserveKey(key string, fingerprint string) ([]byte, bool, error) {
+	claimed, err := r.client.SetNX(idempotencyKey+key, record(fingerprint, nil), reservation).Result()
+	if err != nil {
+		return nil, false, errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
+	}
+
+	if claimed {
+		return nil, false, nil
+	}
+
+	value, err := r.client.Get(idempotencyKey + key).Bytes()
+	if err == redis.Nil {
+		// Expired right now, the request can only be sent again later.
+		return nil, false, appErr.ErrRequestInProgress
+	}
+
+	if err != nil {
+		return nil, false, errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
+	}
+
+	stored, response, ok := parseRecord(value)
+	if !ok {
+		stored = fingerprint
+	}
+	return replay(stored, fingerprint, response)
+}
+
+func (r *RedisIdempotency) CompleteKey(key string, fingerprint string, response []byte) error {
+	if err := r.client.Set(idempotencyKey+key, record(fingerprint, response), IdempotencyTTL).Err(); err != nil {
+		return errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
+	}
+
+	return nil
+}
+
+func (r *RedisIdempotency) ReleaseKey(key string) error {
+	if err := r.client.Del(idempotencyKey + key).Err(); err != nil {
+		return errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
+	}
+
+	return nil
+}
+
+// record encodes the value RedisIdempotency stores for a key, fingerprints
+// are hex so they never hold a newline.
+func record(fingerprint string, response []byte) []byte {
+	return append([]byte(fingerprint+"\n"), response...)
+}
+
+// parseRecord splits a stored value in fingerprint and response. Values
+// stored before fingerprints existed are a bare JSON response, which never
+// holds a newline, and have none.
+func parseRecord(value []byte) (string, []byte, bool) {
+	if i := bytes.IndexByte(value, '\n'); i >= 0 {
+		return string(value[:i]), value[i+1:], true
+	}
+
+	return "", value, false
+}
+
+// replay returns the response stored for a key, claimed by the request with
+// the fingerprint stored, to a request with the fingerprint sent.
+func replay(stored, sent string, response []byte) ([]byte, bool, error) {
+	if stored != sent {
+		return nil, false, appErr.ErrIdempotencyKeyReused
+	}
+
+	if len(response) == 0 {
+		return nil, false, appErr.ErrRequestInProgress
+	}
+
+	return response, true, nil
+}
+
+// InMemoryIdempotency is the in-process IdempotencyI, with the same
+// reservation and expiration as RedisIdempotency.
+type InMemoryIdempotency struct {
+	mu      sync.Mutex
+	entries map[string]idempotencyEntry
+	now     func() time.Time
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	response    []byte
+	expires     time.Time
+}
+
+func NewInMemoryIdempotency() *InMemoryIdempotency {
+	return &InMemoryIdempotency{
+		entries: make(map[string]idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+func (i *InMemoryIdempotency) ReserveKey(key string, fingerprint string) ([]byte, bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entry, ok := i.entries[key]
+	if !ok || !i.now().Before(entry.expires) {
+		i.entries[key] = idempotencyEntry{fingerprint: fingerprint, expires: i.now().Add(reservation)}
+		return nil, false, nil
+	}
+
+	return replay(entry.fingerprint, fingerprint, entry.response)
+}
+
+func (i *InMemoryIdempotency) CompleteKey(key string, fingerprint string, response []byte) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.entries[key] = idempotencyEntry{
+		fingerprint: fingerprint,
+		response:    response,
+		expires:     i.now().Add(IdempotencyTTL),
+	}
+	return nil
+}
+
+func (i *InMemoryIdempotency) ReleaseKey(key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.entries, key)
+	return nil
+}
@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InMemoryRepository keeps the appointments in the process, with the same
+// semantics as MongoRepository, including the IDs it hands out. It is safe
+// for concurrent use and meant for local development and tests, nothing
+// outlives the process.
+type InMemoryRepository struct {
+	mu   sync.RWMutex
+	apps map[string]model.Appointment
+	// tx runs one transaction at a time.
+	tx sync.Mutex
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		apps: make(map[string]model.Appointment),
+	}
+}
+
+type inMemoryTxKey struct{}
+
+// inMemoryTx holds how to undo the writes made in a transaction.
+type inMemoryTx struct {
+	mu   sync.Mutex
+	undo []func()
+}
+
+// onRollback registers undo to run if the transaction of ctx fails, writes
+// made outside of a transaction cannot be undone.
+func onRollback(ctx context.Context, undo func()) {
+	tx, ok := ctx.Value(inMemoryTxKey{}).(*inMemoryTx)
+	if !ok {
+		return
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.undo = append(tx.undo, undo)
+}
+
+func (tx *inMemoryTx) rollback() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+// WithTransaction runs fn, undoing its writes when it fails. Transactions
+// run one at a time, and fn joins the transaction ctx is already in.
+func (m *InMemoryRepository) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	if _, ok := ctx.Value(inMemoryTxKey{}).(*inMemoryTx); ok {
+		return fn(ctx)
+	}
+
+	m.tx.Lock()
+	defer m.tx.Unlock()
+
+	tx := new(inMemoryTx)
+	if err := fn(context.WithValue(ctx, inMemoryTxKey{}, tx)); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return nil
+}
+
+// put stores app, m.mu must be held.
+func (m *InMemoryRepository) put(ctx context.Context, app model.Appointment) {
+	old, existed := m.apps[app.ID]
+	m.apps[app.ID] = app
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if existed {
+			m.apps[app.ID] = old
+			return
+		}
+		delete(m.apps, app.ID)
+	})
+}
+
+// remove deletes the appointment id, m.mu must be held.
+func (m *InMemoryRepository) remove(ctx context.Context, id string) {
+	old, existed := m.apps[id]
+	if !existed {
+		return
+	}
+
+	delete(m.apps, id)
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.apps[id] = old
+	})
+}
+
+func (m *InMemoryRepository) CreateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if app.ID == "" {
+		app.ID = primitive.NewObjectID().Hex()
+	}
+
+	if _, ok := m.apps[app.ID]; ok {
+		return nil, errors.Wrapf(appErr.ErrDatabase, "duplicate key %s", app.ID)
+	}
+
+	app.Version = 1
+	m.put(ctx, app)
+	return &app, nil
+}
+
+// CreateAppointments stores the given slots and returns the stored ones.
+// Slots overlapping one already stored, or one stored before them, are
+// skipped.
+func (m *InMemoryRepository) CreateAppointments(ctx context.Context, apps []model.Appointment) ([]model.Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := make([]model.Appointment, 0, len(m.apps))
+	for _, app := range m.apps {
+		stored = append(stored, app)
+	}
+	taken := newTakenSlots(stored)
+
+	created := make([]model.Appointment, 0, len(apps))
+	for _, app := range apps {
+		if !taken.add(app) {
+			continue
+		}
+		app.ID = primitive.NewObjectID().Hex()
+		app.Version = 1
+		m.put(ctx, app)
+		created = append(created, app)
+	}
+
+	return created, nil
+}
+
+func (m *InMemoryRepository) UpdateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(app.ID); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.apps[app.ID]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+
+	if stored.Version != app.Version {
+		return nil, appErr.ErrVersionMismatch
+	}
+
+	app.Version++
+	m.put(ctx, app)
+	return &app, nil
+}
+
+func (m *InMemoryRepository) DeleteAppointment(ctx context.Context, id string, version int64) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.apps[id]
+	if !ok {
+		return appErr.ErrNotFound
+	}
+
+	if stored.Version != version {
+		return appErr.ErrVersionMismatch
+	}
+
+	m.remove(ctx, id)
+	return nil
+}
+
+func (m *InMemoryRepository) MakeAppointment(ctx context.Context, id string, user int) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, ok := m.apps[id]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+
+	if state := app.State(); state != model.StatusAvailable && state != model.StatusCancelled {
+		return nil, appErr.ErrAlreadyBooked
+	}
+
+	app.UserID = user
+	app.Status = model.StatusBooked
+	app.Version++
+	m.put(ctx, app)
+	return &app, nil
+}
+
+func (m *InMemoryRepository) CancelAppointment(ctx context.Context, id string, user int, version int64) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, ok := m.apps[id]
+	if !ok {
+		return appErr.ErrNotFound
+	}
+
+	if app.UserID != user {
+		return appErr.ErrNotOwner
+	}
+
+	if app.Version != version {
+		return appErr.ErrVersionMismatch
+	}
+
+	if state := app.State(); state != model.StatusBooked && state != model.StatusConfirmed {
+		return appErr.ErrInvalidTransition
+	}
+
+	app.Status = model.StatusCancelled
+	app.Version++
+	m.put(ctx, app)
+	return nil
+}
+
+func (m *InMemoryRepository) UpdateStatus(ctx context.Context, id string, from, to model.Status) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	app, ok := m.apps[id]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+
+	if app.State() != from {
+		return nil, errors.Wrapf(appErr.ErrInvalidTransition, "appointment is no longer %s", from)
+	}
+
+	app.Status = to
+	app.Version++
+	m.put(ctx, app)
+	return &app, nil
+}
+
+func (m *InMemoryRepository) FindAppointmentByID(ctx context.Context, id string) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	app, ok := m.apps[id]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+
+	return &app, nil
+}
+
+func (m *InMemoryRepository) FindAllAppointments(ctx context.Context, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(func(model.Appointment) bool { return true }, opts)
+}
+
+func (m *InMemoryRepository) FindAppointmentByUserID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(func(app model.Appointment) bool { return app.UserID == id }, opts)
+}
+
+func (m *InMemoryRepository) FindAppointmentBySalonID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(func(app model.Appointment) bool { return app.SalonID == id }, opts)
+}
+
+func (m *InMemoryRepository) FindAppointmentByProfessionalID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(func(app model.Appointment) bool { return app.ProfessionalID == id }, opts)
+}
+
+// AvaiableAppointment only returns slots that have not started yet.
+func (m *InMemoryRepository) AvaiableAppointment(ctx context.Context, find model.FindAvailable) (*model.AppointmentPage, error) {
+	now := time.Now()
+	salons := make(map[int]bool, len(find.SalonIDs))
+	for _, id := range find.SalonIDs {
+		salons[id] = true
+	}
+	services := make(map[model.ServiceType]bool, len(find.ServiceTypes))
+	for _, st := range find.ServiceTypes {
+		services[st] = true
+	}
+
+	return m.findPage(func(app model.Appointment) bool {
+		if state := app.State(); state != model.StatusAvailable && state != model.StatusCancelled {
+			return false
+		}
+
+		return app.AppointmentDate.After(now) &&
+			(len(salons) == 0 || salons[app.SalonID]) &&
+			(len(services) == 0 || services[app.ServiceType])
+	}, find.ListOptions)
+}
+
+// HasOverlap reports whether another slot of the same professional is running
+// at any moment between the start and the end of app. Slots with no
+// professional are checked against the other unassigned slots of the salon.
+// The appointment itself is ignored, so it can be moved.
+func (m *InMemoryRepository) HasOverlap(ctx context.Context, app model.Appointment) (bool, error) {
+	if app.ID != "" {
+		if _, err := primitive.ObjectIDFromHex(app.ID); err != nil {
+			return false, errors.Wrap(appErr.ErrInvalidID, err.Error())
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, other := range m.apps {
+		if other.ID == app.ID || other.SalonID != app.SalonID || other.ProfessionalID != app.ProfessionalID {
+			continue
+		}
+
+		if overlaps(app, other) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// findPage returns the page of appointments matching match selected by opts,
+// ordered by date and ID like MongoRepository.findPage.
+func (m *InMemoryRepository) findPage(match func(model.Appointment) bool, opts model.ListOptions) (*model.AppointmentPage, error) {
+	var (
+		cursor model.PageCursor
+		err    error
+	)
+	if opts.Page != "" {
+		if cursor, err = model.DecodePageToken(opts.Page); err != nil {
+			return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+
+		if _, err := primitive.ObjectIDFromHex(cursor.ID); err != nil {
+			return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+	}
+
+	m.mu.RLock()
+	apps := make([]model.Appointment, 0)
+	for _, app := range m.apps {
+		if !match(app) {
+			continue
+		}
+		if !opts.From.IsZero() && app.AppointmentDate.Before(opts.From) {
+			continue
+		}
+		if !opts.To.IsZero() && !app.AppointmentDate.Before(opts.To) {
+			continue
+		}
+		apps = append(apps, app)
+	}
+	m.mu.RUnlock()
+
+	// before reports whether a comes before b in the list order.
+	before := func(a, b model.Appointment) bool {
+		if !a.AppointmentDate.Equal(b.AppointmentDate) {
+			return a.AppointmentDate.Before(b.AppointmentDate) != opts.Descending()
+		}
+		if opts.Descending() {
+			return a.ID > b.ID
+		}
+		return a.ID < b.ID
+	}
+	sort.Slice(apps, func(i, j int) bool { return before(apps[i], apps[j]) })
+
+	page := model.AppointmentPage{Total: int64(len(apps))}
+	if opts.Page != "" {
+		last := model.Appointment{ID: cursor.ID, AppointmentDate: cursor.Date}
+		apps = apps[sort.Search(len(apps), func(i int) bool { return before(last, apps[i]) }):]
+	}
+
+	if size := opts.PageSize(); len(apps) > size {
+		apps = apps[:size]
+		page.NextPage = model.EncodePageToken(apps[size-1])
+	}
+	page.Appointments = apps
+
+	return &page, nil
+}
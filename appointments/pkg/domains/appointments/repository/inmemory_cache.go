@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+)
+
+// InMemoryCache stores the entries of CachedRepository in the process. The
+// values are copied in and out, as they are by Redis.
+type InMemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func NewInMemoryCache() *InMemoryCache {
+	return &InMemoryCache{
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get returns the entry of key, a missing or expired entry fails with
+// ErrNotFound.
+func (c *InMemoryCache) Get(key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+
+	return append([]byte{}, entry.value...), nil
+}
+
+func (c *InMemoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: append([]byte{}, value...), expires: c.now().Add(ttl)}
+	return nil
+}
+
+func (c *InMemoryCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryRepository_WithTransaction(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+
+	app, err := repo.CreateAppointment(ctx, model.Appointment{
+		SalonID:         1,
+		AppointmentDate: time.Date(2030, time.June, 23, 21, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.DeleteAppointment(ctx, app.ID, 1); err != nil {
+			return err
+		}
+		return repo.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := repo.CreateAppointment(ctx, model.Appointment{SalonID: 2})
+			return err
+		})
+	})
+	require.NoError(t, err)
+	_, err = repo.FindAppointmentByID(ctx, app.ID)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+
+	app, err = repo.CreateAppointment(ctx, *app)
+	require.NoError(t, err)
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.DeleteAppointment(ctx, app.ID, 1); err != nil {
+			return err
+		}
+		return repo.WithTransaction(ctx, func(ctx context.Context) error {
+			if _, err := repo.CreateAppointment(ctx, model.Appointment{SalonID: 3}); err != nil {
+				return err
+			}
+			return appErr.ErrNew
+		})
+	})
+	assert.ErrorIs(t, err, appErr.ErrNew)
+
+	stored, err := repo.FindAppointmentByID(ctx, app.ID)
+	require.NoError(t, err, "the delete is undone")
+	assert.Equal(t, *app, *stored)
+	page, err := repo.FindAppointmentBySalonID(ctx, 3, model.ListOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, page.Total, "the nested transaction is undone with the one it joined")
+}
+
+func TestInMemoryRepository_WithTransactionSerialized(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = repo.WithTransaction(ctx, func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	second := make(chan struct{})
+	go func() {
+		_ = repo.WithTransaction(ctx, func(context.Context) error {
+			close(second)
+			return nil
+		})
+	}()
+
+	select {
+	case <-second:
+		t.Fatal("a transaction started while another one was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+	<-second
+}
+
+func TestInMemoryCache(t *testing.T) {
+	cache := NewInMemoryCache()
+	now := time.Date(2030, time.June, 23, 9, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set("629aac9c363519d9a9615369", []byte(`{}`), time.Hour))
+	require.NoError(t, cache.Set("user_1", []byte(`{}`), time.Minute))
+
+	now = now.Add(time.Minute - time.Nanosecond)
+	_, err := cache.Get("user_1")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Nanosecond)
+	_, err = cache.Get("user_1")
+	assert.ErrorIs(t, err, appErr.ErrNotFound, "expired at its ttl")
+	_, err = cache.Get("629aac9c363519d9a9615369")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = cache.Get("629aac9c363519d9a9615369")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	assert.Empty(t, cache.entries, "expired entries are dropped")
+}
+
+func TestInMemoryIdempotency(t *testing.T) {
+	store := NewInMemoryIdempotency()
+	now := time.Date(2030, time.June, 23, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	_, done, err := store.ReserveKey("key", "fingerprint")
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	_, _, err = store.ReserveKey("key", "fingerprint")
+	assert.ErrorIs(t, err, appErr.ErrRequestInProgress)
+
+	require.NoError(t, store.CompleteKey("key", "fingerprint", []byte(`{}`)))
+	response, done, err := store.ReserveKey("key", "fingerprint")
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []byte(`{}`), response)
+
+	_, done, err = store.ReserveKey("key", "another")
+	assert.ErrorIs(t, err, appErr.ErrIdempotencyKeyReused, "a key cannot be reused by another request")
+	assert.False(t, done)
+
+	now = now.Add(IdempotencyTTL)
+	_, done, err = store.ReserveKey("key", "fingerprint")
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	require.NoError(t, store.ReleaseKey("key"))
+	_, done, err = store.ReserveKey("key", "fingerprint")
+	require.NoError(t, err)
+	assert.False(t, done)
+}
@@ -0,0 +1,28 @@
+-- btree_gist lets the exclusion constraint compare integers with =.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE appointments (
+    id               CHAR(24)    PRIMARY KEY,
+    user_id          INTEGER     NOT NULL DEFAULT 0,
+    salon_id         INTEGER     NOT NULL,
+    professional_id  INTEGER     NOT NULL DEFAULT 0,
+    appointment_date TIMESTAMPTZ NOT NULL,
+    end_date         TIMESTAMPTZ NOT NULL,
+    service_type     TEXT        NOT NULL DEFAULT '',
+    price_cents      BIGINT      NOT NULL DEFAULT 0,
+    status           TEXT        NOT NULL,
+    version          BIGINT      NOT NULL DEFAULT 1,
+    -- A professional, or the unassigned slots of a salon, never holds two
+    -- slots running at the same moment. HasOverlap checks it first, the
+    -- constraint keeps two concurrent writes from double-booking anyway.
+    CONSTRAINT appointments_no_overlap EXCLUDE USING gist (
+        salon_id WITH =,
+        professional_id WITH =,
+        tstzrange(appointment_date, end_date) WITH &&
+    )
+);
+
+CREATE INDEX appointments_date ON appointments (appointment_date, id);
+CREATE INDEX appointments_user_date ON appointments (user_id, appointment_date, id);
+CREATE INDEX appointments_salon_date ON appointments (salon_id, appointment_date, id);
+CREATE INDEX appointments_professional_date ON appointments (professional_id, appointment_date, id);
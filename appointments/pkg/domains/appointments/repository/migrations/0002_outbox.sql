@@ -0,0 +1,13 @@
+CREATE TABLE outbox (
+    id         CHAR(24)    PRIMARY KEY,
+    key        TEXT        NOT NULL UNIQUE,
+    type       TEXT        NOT NULL,
+    payload    BYTEA       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    sent_at    TIMESTAMPTZ,
+    attempts   INTEGER     NOT NULL DEFAULT 0,
+    last_error TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_pending ON outbox (id) WHERE sent_at IS NULL;
+CREATE INDEX outbox_sent ON outbox (sent_at) WHERE sent_at IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE outbox ADD COLUMN parked_at TIMESTAMPTZ;
+
+DROP INDEX outbox_pending;
+CREATE INDEX outbox_pending ON outbox (id) WHERE sent_at IS NULL AND parked_at IS NULL;
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
@@ -27,176 +27,547 @@ func NewMongoRepostory(client *mongo.Client, database, collection string) *Mongo
 	}
 }
 
+// EnsureIndexes creates the indexes the queries of the repository rely on.
+// It is idempotent, so it is safe to call on every startup.
+func (m *MongoRepository) EnsureIndexes(ctx context.Context) error {
+	coll := m.client.Database(m.database).Collection(m.collection)
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "salon_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "appointment_date", Value: 1},
+			},
+			Options: options.Index().SetName("salon_user_date"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "professional_id", Value: 1},
+				{Key: "appointment_date", Value: 1},
+			},
+			Options: options.Index().SetName("professional_date"),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return nil
+}
+
+// WithTransaction runs fn in a Mongo transaction, which requires a replica
+// set. fn may run more than once when the transaction is retried.
+func (m *MongoRepository) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	var fnErr error
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		fnErr = fn(sc)
+		return nil, fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return nil
+}
+
 func (m *MongoRepository) CreateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
 	coll := m.client.Database(m.database).Collection(m.collection)
+	app.Version = 1
 	result, err := coll.InsertOne(ctx, &app)
 	if err != nil {
 		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
-	id, err := primitive.ObjectIDFromHex(fmt.Sprintf("%v", result.InsertedID))
-	if err != nil {
-		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	id, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.Wrapf(appErr.ErrDatabase, "unexpected inserted id %v", result.InsertedID)
 	}
 	app.ID = id.Hex()
 	return &app, nil
 }
 
+// CreateAppointments inserts the given slots in a single InsertMany and
+// returns the inserted ones. Slots overlapping one already stored, or one
+// inserted before them, are skipped like PostgresRepository does, so
+// generating the same slots twice is harmless.
+func (m *MongoRepository) CreateAppointments(ctx context.Context, apps []model.Appointment) ([]model.Appointment, error) {
+	if len(apps) == 0 {
+		return []model.Appointment{}, nil
+	}
+
+	coll := m.client.Database(m.database).Collection(m.collection)
+	salons := make([]int, 0)
+	seen := make(map[int]bool)
+	first, last := apps[0].AppointmentDate, apps[0].End()
+	for _, app := range apps {
+		if !seen[app.SalonID] {
+			seen[app.SalonID] = true
+			salons = append(salons, app.SalonID)
+		}
+		if app.AppointmentDate.Before(first) {
+			first = app.AppointmentDate
+		}
+		if app.End().After(last) {
+			last = app.End()
+		}
+	}
+
+	filter := bson.M{
+		"salon_id":         bson.M{"$in": salons},
+		"appointment_date": bson.M{"$lt": last},
+		"$or": bson.A{
+			bson.M{"end_date": bson.M{"$gt": first}},
+			// Slots stored before end_date existed last model.SlotDuration.
+			bson.M{
+				"end_date":         bson.M{"$exists": false},
+				"appointment_date": bson.M{"$gt": first.Add(-model.SlotDuration)},
+			},
+		},
+	}
+	projection := options.Find().SetProjection(bson.M{
+		"salon_id": 1, "professional_id": 1, "appointment_date": 1, "end_date": 1,
+	})
+	cursor, err := coll.Find(ctx, filter, projection)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	var existing []model.Appointment
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	taken := newTakenSlots(existing)
+	docs := make([]interface{}, 0, len(apps))
+	created := make([]model.Appointment, 0, len(apps))
+	for _, app := range apps {
+		if !taken.add(app) {
+			continue
+		}
+		app.ID = ""
+		app.Version = 1
+		docs = append(docs, app)
+		created = append(created, app)
+	}
+
+	if len(docs) == 0 {
+		return created, nil
+	}
+
+	result, err := coll.InsertMany(ctx, docs)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	for i, id := range result.InsertedIDs {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			created[i].ID = oid.Hex()
+		}
+	}
+
+	return created, nil
+}
+
+// takenSlots are the slots taken by each professional of each salon, for
+// CreateAppointments to skip the ones overlapping them.
+type takenSlots map[[2]int][]model.Appointment
+
+func newTakenSlots(taken []model.Appointment) takenSlots {
+	s := make(takenSlots)
+	for _, app := range taken {
+		key := [2]int{app.SalonID, app.ProfessionalID}
+		s[key] = append(s[key], app)
+	}
+
+	return s
+}
+
+// add takes the slot of app, unless it overlaps a slot already taken.
+func (s takenSlots) add(app model.Appointment) bool {
+	key := [2]int{app.SalonID, app.ProfessionalID}
+	for _, other := range s[key] {
+		if overlaps(app, other) {
+			return false
+		}
+	}
+
+	s[key] = append(s[key], app)
+	return true
+}
+
+// overlaps reports whether a and b run at the same moment, they are assumed
+// to be of the same salon professional.
+func overlaps(a, b model.Appointment) bool {
+	return a.AppointmentDate.Before(b.End()) && b.AppointmentDate.Before(a.End())
+}
+
+// UpdateAppointment replaces the appointment if it is still at app.Version,
+// and returns it at the next version. Fields left empty are cleared.
 func (m *MongoRepository) UpdateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
 	coll := m.client.Database(m.database).Collection(m.collection)
 	id, err := primitive.ObjectIDFromHex(app.ID)
 	if err != nil {
-		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
 	}
 
+	filter := bson.M{"_id": id, "$or": versionFilter(app.Version)}
 	app.ID = ""
-	if _, err := coll.UpdateByID(ctx, id, bson.M{"$set": &app}); err != nil {
+	app.Version++
+	result, err := coll.ReplaceOne(ctx, filter, &app)
+	if err != nil {
 		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
+	if result.MatchedCount == 0 {
+		return nil, versionMismatch(ctx, coll, id)
+	}
+
 	app.ID = id.Hex()
 
 	return &app, nil
 }
 
-func (m *MongoRepository) DeleteAppointment(ctx context.Context, id string) error {
+// DeleteAppointment deletes the appointment if it is still at version.
+func (m *MongoRepository) DeleteAppointment(ctx context.Context, id string, version int64) error {
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.Wrap(appErr.ErrDatabase, err.Error())
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
 	}
 
 	coll := m.client.Database(m.database).Collection(m.collection)
-	result, err := coll.DeleteOne(ctx, bson.M{"_id": _id})
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": _id, "$or": versionFilter(version)})
 	if err != nil {
-		return err
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
 	if result.DeletedCount == 0 {
-		return appErr.ErrNotFound
+		return versionMismatch(ctx, coll, _id)
 	}
 
 	return nil
 }
 
-func (m *MongoRepository) FindAllAppointments(ctx context.Context) ([]model.Appointment, error) {
-	app := make([]model.Appointment, 0)
-	coll := m.client.Database(m.database).Collection(m.collection)
-	cur, err := coll.Find(ctx, bson.D{}, options.Find())
-	if err != nil {
-		return nil, err
-	}
-	if err := cur.All(ctx, &app); err != nil {
-		return nil, err
-	}
-
-	return app, nil
+func (m *MongoRepository) FindAllAppointments(ctx context.Context, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(ctx, bson.M{}, opts)
 }
 
 func (m *MongoRepository) FindAppointmentByID(ctx context.Context, id string) (*model.Appointment, error) {
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
 	}
 
 	var app model.Appointment
 	coll := m.client.Database(m.database).Collection(m.collection)
-	if err := coll.FindOne(ctx, bson.M{"_id": _id}).Decode(&app); err != nil {
-		return nil, err
+	err = coll.FindOne(ctx, bson.M{"_id": _id}).Decode(&app)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, appErr.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 	return &app, nil
 }
 
+// HasOverlap reports whether another slot of the same professional is running
+// at any moment between the start and the end of app. Slots with no
+// professional are checked against the other unassigned slots of the salon.
+// The appointment itself is ignored, so it can be moved.
+func (m *MongoRepository) HasOverlap(ctx context.Context, app model.Appointment) (bool, error) {
+	filter := bson.M{
+		"salon_id":         app.SalonID,
+		"professional_id":  professionalFilter(app.ProfessionalID),
+		"appointment_date": bson.M{"$lt": app.End()},
+		"$or": bson.A{
+			bson.M{"end_date": bson.M{"$gt": app.AppointmentDate}},
+			// Slots stored before end_date existed last model.SlotDuration.
+			bson.M{
+				"end_date":         bson.M{"$exists": false},
+				"appointment_date": bson.M{"$gt": app.AppointmentDate.Add(-model.SlotDuration)},
+			},
+		},
+	}
+	if app.ID != "" {
+		_id, err := primitive.ObjectIDFromHex(app.ID)
+		if err != nil {
+			return false, errors.Wrap(appErr.ErrInvalidID, err.Error())
+		}
+		filter["_id"] = bson.M{"$ne": _id}
+	}
+
+	coll := m.client.Database(m.database).Collection(m.collection)
+	count, err := coll.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return count > 0, nil
+}
+
 func (m *MongoRepository) MakeAppointment(ctx context.Context, id string, user int) (*model.Appointment, error) {
 	var app model.Appointment
 	coll := m.client.Database(m.database).Collection(m.collection)
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
 	}
 
-	if err := coll.FindOne(ctx, bson.M{"_id": _id}).Decode(&app); err != nil {
-		return nil, err
+	// The status condition makes the booking a single atomic compare-and-set,
+	// so only one of many concurrent bookers can take the slot.
+	filter := bson.M{"_id": _id, "$or": statusFilter(model.StatusAvailable, model.StatusCancelled)}
+	update := bson.M{"$set": bson.M{"user_id": user, "status": model.StatusBooked}, "$inc": bson.M{"version": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&app)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		count, err := coll.CountDocuments(ctx, bson.M{"_id": _id})
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+
+		if count == 0 {
+			return nil, appErr.ErrNotFound
+		}
+
+		return nil, appErr.ErrAlreadyBooked
 	}
-	app.ID = ""
-	app.UserID = user
-	if _, err := coll.UpdateByID(ctx, _id, bson.M{"$set": &app}); err != nil {
+
+	if err != nil {
 		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
-	app.ID = _id.Hex()
-
 	return &app, nil
 }
 
-func (m *MongoRepository) FindAppointmentByUserID(ctx context.Context, id int) ([]model.Appointment, error) {
-	app := make([]model.Appointment, 0)
-	filter := bson.M{"user_id": id}
-	coll := m.client.Database(m.database).Collection(m.collection)
-	cursor, err := coll.Find(ctx, filter, options.Find())
-	if err != nil {
-		return nil, err
-	}
+func (m *MongoRepository) FindAppointmentByUserID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(ctx, bson.M{"user_id": id}, opts)
+}
+
+func (m *MongoRepository) FindAppointmentBySalonID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(ctx, bson.M{"salon_id": id}, opts)
+}
+
+func (m *MongoRepository) FindAppointmentByProfessionalID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return m.findPage(ctx, bson.M{"professional_id": id}, opts)
+}
 
-	if err = cursor.All(ctx, &app); err != nil {
-		return nil, err
+// AvaiableAppointment only returns slots that have not started yet.
+func (m *MongoRepository) AvaiableAppointment(ctx context.Context, find model.FindAvailable) (*model.AppointmentPage, error) {
+	filter := bson.M{
+		"$or":              statusFilter(model.StatusAvailable, model.StatusCancelled),
+		"appointment_date": bson.M{"$gt": time.Now()},
+	}
+	if len(find.SalonIDs) > 0 {
+		filter["salon_id"] = bson.M{"$in": find.SalonIDs}
+	}
+	if len(find.ServiceTypes) > 0 {
+		filter["service_type"] = bson.M{"$in": find.ServiceTypes}
 	}
 
-	return app, nil
+	return m.findPage(ctx, filter, find.ListOptions)
 }
 
-func (m *MongoRepository) FindAppointmentBySalonID(ctx context.Context, id int) ([]model.Appointment, error) {
-	app := make([]model.Appointment, 0)
-	filter := bson.M{"salon_id": id}
+// findPage returns the page of documents matching filter selected by opts.
+// Pages are ordered by appointment_date and _id, and the next page starts
+// strictly after the last document of the current one, so results stay
+// stable while appointments are inserted.
+func (m *MongoRepository) findPage(ctx context.Context, filter bson.M, opts model.ListOptions) (*model.AppointmentPage, error) {
 	coll := m.client.Database(m.database).Collection(m.collection)
-	cursor, err := coll.Find(ctx, filter, options.Find())
+	if !opts.From.IsZero() || !opts.To.IsZero() {
+		date := bson.M{}
+		if !opts.From.IsZero() {
+			date["$gte"] = opts.From
+		}
+		if !opts.To.IsZero() {
+			date["$lt"] = opts.To
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"appointment_date": date}}}
+	}
+
+	total, err := coll.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
-	if err = cursor.All(ctx, &app); err != nil {
-		return nil, err
+	order, after := 1, "$gt"
+	if opts.Descending() {
+		order, after = -1, "$lt"
 	}
 
-	return app, nil
-}
+	if opts.Page != "" {
+		cursor, err := model.DecodePageToken(opts.Page)
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+
+		lastID, err := primitive.ObjectIDFromHex(cursor.ID)
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"appointment_date": bson.M{after: cursor.Date}},
+			bson.M{"appointment_date": cursor.Date, "_id": bson.M{after: lastID}},
+		}}}}
+	}
 
-func (m *MongoRepository) AvaiableAppointment(ctx context.Context) ([]model.Appointment, error) {
-	app := make([]model.Appointment, 0)
-	coll := m.client.Database(m.database).Collection(m.collection)
-	filter := bson.M{"user_id": 0}
-	cur, err := coll.Find(ctx, filter, options.Find())
+	size := opts.PageSize()
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "appointment_date", Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(int64(size + 1))
+	cur, err := coll.Find(ctx, filter, findOpts)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
+	app := make([]model.Appointment, 0)
 	if err := cur.All(ctx, &app); err != nil {
-		return nil, err
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	page := model.AppointmentPage{Total: total}
+	if len(app) > size {
+		app = app[:size]
+		page.NextPage = model.EncodePageToken(app[size-1])
 	}
-	return app, nil
+	page.Appointments = app
+
+	return &page, nil
 }
 
-func (m *MongoRepository) CancelAppointment(ctx context.Context, id string, user int) error {
+func (m *MongoRepository) CancelAppointment(ctx context.Context, id string, user int, version int64) error {
 	var app model.Appointment
 	_id, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.Wrap(appErr.ErrDatabase, err.Error())
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
 	}
 	coll := m.client.Database(m.database).Collection(m.collection)
-	if err := coll.FindOne(ctx, bson.M{"_id": _id}).Decode(&app); err != nil {
+	filter := bson.M{
+		"_id":     _id,
+		"user_id": user,
+		"$and": bson.A{
+			bson.M{"$or": statusFilter(model.StatusBooked, model.StatusConfirmed)},
+			bson.M{"$or": versionFilter(version)},
+		},
+	}
+	update := bson.M{"$set": bson.M{"status": model.StatusCancelled}, "$inc": bson.M{"version": 1}}
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
 		return errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
-	if app.UserID != user {
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	err = coll.FindOne(ctx, bson.M{"_id": _id}).Decode(&app)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return appErr.ErrNotFound
+	}
+
+	if err != nil {
 		return errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
-	app.ID = ""
-	app.UserID = 0
-	result, err := coll.UpdateByID(ctx, _id, bson.M{"$set": &app})
+	if app.UserID != user {
+		return appErr.ErrNotOwner
+	}
+	if app.Version != version {
+		return appErr.ErrVersionMismatch
+	}
+
+	return appErr.ErrInvalidTransition
+}
+
+func (m *MongoRepository) UpdateStatus(ctx context.Context, id string, from, to model.Status) (*model.Appointment, error) {
+	var app model.Appointment
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	coll := m.client.Database(m.database).Collection(m.collection)
+	filter := bson.M{"_id": _id, "$or": statusFilter(from)}
+	update := bson.M{"$set": bson.M{"status": to}, "$inc": bson.M{"version": 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&app)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		count, err := coll.CountDocuments(ctx, bson.M{"_id": _id})
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+
+		if count == 0 {
+			return nil, appErr.ErrNotFound
+		}
+
+		return nil, errors.Wrapf(appErr.ErrInvalidTransition, "appointment is no longer %s", from)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return &app, nil
+}
+
+// versionFilter matches documents at the given version. Documents written
+// before the version field existed are at version 0.
+func versionFilter(version int64) bson.A {
+	filter := bson.A{bson.M{"version": version}}
+	if version == 0 {
+		filter = append(filter, bson.M{"version": bson.M{"$exists": false}})
+	}
+
+	return filter
+}
+
+// versionMismatch tells why a conditional write on id matched nothing,
+// either the appointment is gone or it is at another version.
+func versionMismatch(ctx context.Context, coll *mongo.Collection, id primitive.ObjectID) error {
+	count, err := coll.CountDocuments(ctx, bson.M{"_id": id})
 	if err != nil {
 		return errors.Wrap(appErr.ErrDatabase, err.Error())
 	}
 
-	if result.ModifiedCount == 0 {
-		return appErr.ErrDatabase
+	if count == 0 {
+		return appErr.ErrNotFound
+	}
+
+	return appErr.ErrVersionMismatch
+}
+
+// statusFilter matches documents in any of the given statuses. Documents
+// written before the status field existed are matched by their user_id.
+func statusFilter(statuses ...model.Status) bson.A {
+	filter := bson.A{bson.M{"status": bson.M{"$in": statuses}}}
+	for _, st := range statuses {
+		switch st {
+		case model.StatusAvailable:
+			filter = append(filter, bson.M{"status": bson.M{"$exists": false}, "user_id": 0})
+		case model.StatusBooked:
+			filter = append(filter, bson.M{"status": bson.M{"$exists": false}, "user_id": bson.M{"$ne": 0}})
+		}
 	}
-	return nil
+
+	return filter
+}
+
+// professionalFilter matches the slots of professional id. Documents stored
+// before professional_id existed have no professional, like id 0.
+func professionalFilter(id int) interface{} {
+	if id == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+
+	return id
 }
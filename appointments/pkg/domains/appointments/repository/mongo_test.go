@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestMongo connects to the database in MONGO_TEST_URI and returns a
+// repository over a throwaway collection, skipping the test when it is unset.
+func newTestMongo(t *testing.T) *MongoRepository {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set, skipping mongo integration test")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+
+	collection := "appointments_test_" + time.Now().Format("20060102150405.000000000")
+	t.Cleanup(func() {
+		_ = client.Database("appointments_test").Collection(collection).Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+
+	repo := NewMongoRepostory(client, "appointments_test", collection)
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	return repo
+}
+
+func TestMongoRepository_EnsureIndexes(t *testing.T) {
+	repo := newTestMongo(t)
+
+	require.NoError(t, repo.EnsureIndexes(context.Background()), "indexing twice is harmless")
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SentOutboxTTL is how long relayed entries are kept before Mongo removes
+// them, pending entries never expire.
+const SentOutboxTTL = 7 * 24 * time.Hour
+
+type MongoOutbox struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+func NewMongoOutbox(client *mongo.Client, database, collection string) *MongoOutbox {
+	return &MongoOutbox{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+// EnsureIndexes creates the indexes the relay relies on, and the unique key
+// that keeps an event from being stored twice.
+func (o *MongoOutbox) EnsureIndexes(ctx context.Context) error {
+	coll := o.client.Database(o.database).Collection(o.collection)
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetName("key").SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "sent_at", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+			Options: options.Index().SetName("pending"),
+		},
+		{
+			Keys: bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetName("sent_ttl").
+				SetExpireAfterSeconds(int32(SentOutboxTTL / time.Second)),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return nil
+}
+
+// AddOutbox stores the entry, an entry whose key is already stored is
+// ignored.
+func (o *MongoOutbox) AddOutbox(ctx context.Context, entry model.OutboxEntry) error {
+	coll := o.client.Database(o.database).Collection(o.collection)
+	if _, err := coll.InsertOne(ctx, &entry); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return nil
+}
+
+// PendingOutbox returns the oldest entries not relayed nor parked yet, in the
+// order they were stored.
+func (o *MongoOutbox) PendingOutbox(ctx context.Context, limit int) ([]model.OutboxEntry, error) {
+	var entries []model.OutboxEntry
+	coll := o.client.Database(o.database).Collection(o.collection)
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	filter := bson.M{"sent_at": bson.M{"$exists": false}, "parked_at": bson.M{"$exists": false}}
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return entries, nil
+}
+
+func (o *MongoOutbox) MarkOutboxSent(ctx context.Context, id string) error {
+	return o.update(ctx, id, bson.M{"$set": bson.M{"sent_at": time.Now().UTC()}})
+}
+
+func (o *MongoOutbox) MarkOutboxFailed(ctx context.Context, id string, reason string) error {
+	return o.update(ctx, id, bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": bson.M{"last_error": reason},
+	})
+}
+
+// ParkOutbox records the last failure of the entry and stops relaying it.
+func (o *MongoOutbox) ParkOutbox(ctx context.Context, id string, reason string) error {
+	return o.update(ctx, id, bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": bson.M{"last_error": reason, "parked_at": time.Now().UTC()},
+	})
+}
+
+func (o *MongoOutbox) update(ctx context.Context, id string, update bson.M) error {
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	coll := o.client.Database(o.database).Collection(o.collection)
+	result, err := coll.UpdateByID(ctx, _id, update)
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	if result.MatchedCount == 0 {
+		return appErr.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOutbox returns an outbox over a throwaway collection of the
+// database of repo.
+func newTestOutbox(t *testing.T, repo *MongoRepository) *MongoOutbox {
+	t.Helper()
+	ctx := context.Background()
+	outbox := NewMongoOutbox(repo.client, repo.database, repo.collection+"_outbox")
+	t.Cleanup(func() {
+		_ = repo.client.Database(outbox.database).Collection(outbox.collection).Drop(ctx)
+	})
+	require.NoError(t, outbox.EnsureIndexes(ctx))
+
+	return outbox
+}
+
+func TestMongoOutbox_Relay(t *testing.T) {
+	repo := newTestMongo(t)
+	outbox := newTestOutbox(t, repo)
+	ctx := context.Background()
+
+	for _, key := range []string{"first", "second", "first"} {
+		require.NoError(t, outbox.AddOutbox(ctx, model.OutboxEntry{
+			Key:       key,
+			Type:      "appointment.created",
+			Payload:   []byte(`{}`),
+			CreatedAt: time.Now().UTC(),
+		}))
+	}
+
+	pending, err := outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2, "an entry whose key is stored is ignored")
+	assert.Equal(t, "first", pending[0].Key)
+	assert.Equal(t, "second", pending[1].Key)
+
+	require.NoError(t, outbox.MarkOutboxFailed(ctx, pending[0].ID, "channel closed"))
+	require.NoError(t, outbox.MarkOutboxSent(ctx, pending[1].ID))
+
+	pending, err = outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "first", pending[0].Key)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "channel closed", pending[0].LastError)
+
+	require.NoError(t, outbox.ParkOutbox(ctx, pending[0].ID, "channel closed"))
+	pending, err = outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "parked entries are not relayed")
+}
+
+// Transactions need MONGO_TEST_URI to point to a replica set.
+func TestMongoRepository_WithTransaction(t *testing.T) {
+	repo := newTestMongo(t)
+	outbox := newTestOutbox(t, repo)
+	ctx := context.Background()
+	slot := model.Appointment{SalonID: 1, AppointmentDate: time.Date(2030, time.June, 23, 21, 0, 0, 0, time.UTC)}
+
+	errRollback := errors.New("rollback")
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateAppointment(ctx, slot); err != nil {
+			return err
+		}
+		if err := outbox.AddOutbox(ctx, model.OutboxEntry{Key: "aborted"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	page, err := repo.FindAllAppointments(ctx, model.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Appointments)
+	pending, err := outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateAppointment(ctx, slot); err != nil {
+			return err
+		}
+		return outbox.AddOutbox(ctx, model.OutboxEntry{Key: "committed"})
+	})
+	require.NoError(t, err)
+
+	page, err = repo.FindAllAppointments(ctx, model.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, page.Appointments, 1)
+	pending, err = outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
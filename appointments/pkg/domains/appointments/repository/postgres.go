@@ -0,0 +1,664 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is the advisory lock instances hold while migrating.
+const migrationLock = 4207173461
+
+// exclusionViolation is the SQLSTATE of a write breaking appointments_no_overlap.
+const exclusionViolation = "23P01"
+
+const appointmentColumns = "id, user_id, salon_id, professional_id, appointment_date, end_date, service_type, price_cents, status, version"
+
+// PostgresRepository stores the appointments in Postgres, with the same
+// semantics and IDs as MongoRepository. The appointments_no_overlap
+// constraint also rejects slots overlapping another one, which Mongo only
+// checks through HasOverlap.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Migrate brings the schema, outbox included, up to date. The migrations
+// are applied once each, in the order of their file names, while holding a
+// lock, so it is safe to call on every startup of every instance.
+func (p *PostgresRepository) Migrate(ctx context.Context) error {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return p.WithTransaction(ctx, func(ctx context.Context) error {
+		conn := postgresConn(ctx, p.db)
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLock); err != nil {
+			return errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+
+		_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			name       TEXT        PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+		if err != nil {
+			return errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+
+		for _, file := range files {
+			name := path.Base(file)
+			var applied bool
+			err := conn.QueryRowContext(ctx,
+				"SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE name = $1)", name,
+			).Scan(&applied)
+			if err != nil {
+				return errors.Wrap(appErr.ErrDatabase, err.Error())
+			}
+
+			if applied {
+				continue
+			}
+
+			script, err := migrations.ReadFile(file)
+			if err != nil {
+				return errors.Wrap(appErr.ErrDatabase, err.Error())
+			}
+
+			if _, err := conn.ExecContext(ctx, string(script)); err != nil {
+				return errors.Wrapf(appErr.ErrDatabase, "migration %s: %s", name, err)
+			}
+
+			if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (name) VALUES ($1)", name); err != nil {
+				return errors.Wrap(appErr.ErrDatabase, err.Error())
+			}
+		}
+
+		return nil
+	})
+}
+
+type postgresTxKey struct{}
+
+// dbtx runs queries, either in a transaction or not.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// postgresConn returns the transaction ctx is in, or db outside of one.
+func postgresConn(ctx context.Context, db *sql.DB) dbtx {
+	if tx, ok := ctx.Value(postgresTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// WithTransaction runs fn in a transaction, rolled back when fn fails. fn
+// joins the transaction ctx is already in.
+func (p *PostgresRepository) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	if _, ok := ctx.Value(postgresTxKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	if err := fn(context.WithValue(ctx, postgresTxKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return nil
+}
+
+func (p *PostgresRepository) CreateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
+	if app.ID == "" {
+		app.ID = primitive.NewObjectID().Hex()
+	}
+	app.Version = 1
+	app = stored(app)
+
+	_, err := postgresConn(ctx, p.db).ExecContext(ctx,
+		"INSERT INTO appointments ("+appointmentColumns+") VALUES "+placeholders(0, 10),
+		appointmentArgs(app)...,
+	)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	return &app, nil
+}
+
+// insertBatch is how many slots CreateAppointments inserts per statement,
+// well below the 65535 parameters a statement takes.
+const insertBatch = 1000
+
+// CreateAppointments inserts the given slots and returns the inserted ones.
+// Slots overlapping one already stored, or one inserted before them, are
+// skipped, so generating the same slots twice is harmless.
+func (p *PostgresRepository) CreateAppointments(ctx context.Context, apps []model.Appointment) ([]model.Appointment, error) {
+	created := make([]model.Appointment, 0, len(apps))
+	for start := 0; start < len(apps); start += insertBatch {
+		end := start + insertBatch
+		if end > len(apps) {
+			end = len(apps)
+		}
+
+		batch, err := p.insertSlots(ctx, apps[start:end])
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, batch...)
+	}
+
+	return created, nil
+}
+
+func (p *PostgresRepository) insertSlots(ctx context.Context, apps []model.Appointment) ([]model.Appointment, error) {
+	slots := make([]model.Appointment, 0, len(apps))
+	values := make([]string, 0, len(apps))
+	args := make([]interface{}, 0, len(apps)*10)
+	for _, app := range apps {
+		app.ID = primitive.NewObjectID().Hex()
+		app.Version = 1
+		app = stored(app)
+		slots = append(slots, app)
+		values = append(values, placeholders(len(args), 10))
+		args = append(args, appointmentArgs(app)...)
+	}
+
+	query := "INSERT INTO appointments (" + appointmentColumns + ") VALUES " + strings.Join(values, ", ") +
+		" ON CONFLICT DO NOTHING RETURNING id"
+	rows, err := postgresConn(ctx, p.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, postgresError(err)
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool, len(slots))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+		inserted[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, postgresError(err)
+	}
+
+	created := make([]model.Appointment, 0, len(inserted))
+	for _, app := range slots {
+		if inserted[app.ID] {
+			created = append(created, app)
+		}
+	}
+
+	return created, nil
+}
+
+// UpdateAppointment replaces the appointment if it is still at app.Version,
+// and returns it at the next version. Fields left empty are cleared.
+func (p *PostgresRepository) UpdateAppointment(ctx context.Context, app model.Appointment) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(app.ID); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	args := appointmentArgs(stored(app))
+	row := postgresConn(ctx, p.db).QueryRowContext(ctx, `UPDATE appointments SET
+		user_id = $2, salon_id = $3, professional_id = $4, appointment_date = $5, end_date = $6,
+		service_type = $7, price_cents = $8, status = $9, version = version + 1
+		WHERE id = $1 AND version = $10
+		RETURNING `+appointmentColumns, args...)
+	updated, err := scanAppointment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, p.versionMismatch(ctx, app.ID)
+	}
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	return &updated, nil
+}
+
+// DeleteAppointment deletes the appointment if it is still at version.
+func (p *PostgresRepository) DeleteAppointment(ctx context.Context, id string, version int64) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	result, err := postgresConn(ctx, p.db).ExecContext(ctx,
+		"DELETE FROM appointments WHERE id = $1 AND version = $2", id, version)
+	if err != nil {
+		return postgresError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return postgresError(err)
+	}
+
+	if deleted == 0 {
+		return p.versionMismatch(ctx, id)
+	}
+
+	return nil
+}
+
+func (p *PostgresRepository) FindAllAppointments(ctx context.Context, opts model.ListOptions) (*model.AppointmentPage, error) {
+	return p.findPage(ctx, where{}, opts)
+}
+
+func (p *PostgresRepository) FindAppointmentByID(ctx context.Context, id string) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	row := postgresConn(ctx, p.db).QueryRowContext(ctx,
+		"SELECT "+appointmentColumns+" FROM appointments WHERE id = $1", id)
+	app, err := scanAppointment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, appErr.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return &app, nil
+}
+
+func (p *PostgresRepository) FindAppointmentByUserID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	var w where
+	w.and("user_id = ?", id)
+	return p.findPage(ctx, w, opts)
+}
+
+func (p *PostgresRepository) FindAppointmentBySalonID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	var w where
+	w.and("salon_id = ?", id)
+	return p.findPage(ctx, w, opts)
+}
+
+func (p *PostgresRepository) FindAppointmentByProfessionalID(ctx context.Context, id int, opts model.ListOptions) (*model.AppointmentPage, error) {
+	var w where
+	w.and("professional_id = ?", id)
+	return p.findPage(ctx, w, opts)
+}
+
+// AvaiableAppointment only returns slots that have not started yet.
+func (p *PostgresRepository) AvaiableAppointment(ctx context.Context, find model.FindAvailable) (*model.AppointmentPage, error) {
+	var w where
+	w.and("status IN (?, ?)", model.StatusAvailable, model.StatusCancelled)
+	w.and("appointment_date > ?", time.Now())
+	if len(find.SalonIDs) > 0 {
+		salons := make([]int64, 0, len(find.SalonIDs))
+		for _, id := range find.SalonIDs {
+			salons = append(salons, int64(id))
+		}
+		w.and("salon_id = ANY(?)", pq.Array(salons))
+	}
+	if len(find.ServiceTypes) > 0 {
+		types := make([]string, 0, len(find.ServiceTypes))
+		for _, st := range find.ServiceTypes {
+			types = append(types, string(st))
+		}
+		w.and("service_type = ANY(?)", pq.Array(types))
+	}
+
+	return p.findPage(ctx, w, find.ListOptions)
+}
+
+// HasOverlap reports whether another slot of the same professional is running
+// at any moment between the start and the end of app. Slots with no
+// professional are checked against the other unassigned slots of the salon.
+// The appointment itself is ignored, so it can be moved.
+func (p *PostgresRepository) HasOverlap(ctx context.Context, app model.Appointment) (bool, error) {
+	var w where
+	w.and("salon_id = ?", app.SalonID)
+	w.and("professional_id = ?", app.ProfessionalID)
+	w.and("appointment_date < ?", app.End())
+	w.and("end_date > ?", app.AppointmentDate)
+	if app.ID != "" {
+		if _, err := primitive.ObjectIDFromHex(app.ID); err != nil {
+			return false, errors.Wrap(appErr.ErrInvalidID, err.Error())
+		}
+		w.and("id <> ?", app.ID)
+	}
+
+	var overlap bool
+	err := postgresConn(ctx, p.db).QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM appointments"+w.String()+")", w.args...,
+	).Scan(&overlap)
+	if err != nil {
+		return false, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return overlap, nil
+}
+
+func (p *PostgresRepository) MakeAppointment(ctx context.Context, id string, user int) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	// The status condition makes the booking a single atomic compare-and-set,
+	// so only one of many concurrent bookers can take the slot.
+	row := postgresConn(ctx, p.db).QueryRowContext(ctx, `UPDATE appointments
+		SET user_id = $2, status = $3, version = version + 1
+		WHERE id = $1 AND status IN ($4, $5)
+		RETURNING `+appointmentColumns,
+		id, user, model.StatusBooked, model.StatusAvailable, model.StatusCancelled)
+	app, err := scanAppointment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		exists, err := p.exists(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, appErr.ErrNotFound
+		}
+
+		return nil, appErr.ErrAlreadyBooked
+	}
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	return &app, nil
+}
+
+func (p *PostgresRepository) CancelAppointment(ctx context.Context, id string, user int, version int64) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	conn := postgresConn(ctx, p.db)
+	result, err := conn.ExecContext(ctx, `UPDATE appointments
+		SET status = $3, version = version + 1
+		WHERE id = $1 AND user_id = $2 AND status IN ($4, $5) AND version = $6`,
+		id, user, model.StatusCancelled, model.StatusBooked, model.StatusConfirmed, version)
+	if err != nil {
+		return postgresError(err)
+	}
+
+	cancelled, err := result.RowsAffected()
+	if err != nil {
+		return postgresError(err)
+	}
+
+	if cancelled > 0 {
+		return nil
+	}
+
+	var (
+		owner  int
+		stored int64
+	)
+	err = conn.QueryRowContext(ctx, "SELECT user_id, version FROM appointments WHERE id = $1", id).Scan(&owner, &stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return appErr.ErrNotFound
+	}
+
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+	if owner != user {
+		return appErr.ErrNotOwner
+	}
+	if stored != version {
+		return appErr.ErrVersionMismatch
+	}
+
+	return appErr.ErrInvalidTransition
+}
+
+func (p *PostgresRepository) UpdateStatus(ctx context.Context, id string, from, to model.Status) (*model.Appointment, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	row := postgresConn(ctx, p.db).QueryRowContext(ctx, `UPDATE appointments
+		SET status = $2, version = version + 1
+		WHERE id = $1 AND status = $3
+		RETURNING `+appointmentColumns, id, to, from)
+	app, err := scanAppointment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		exists, err := p.exists(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, appErr.ErrNotFound
+		}
+
+		return nil, errors.Wrapf(appErr.ErrInvalidTransition, "appointment is no longer %s", from)
+	}
+
+	if err != nil {
+		return nil, postgresError(err)
+	}
+
+	return &app, nil
+}
+
+// findPage returns the page of rows matching w selected by opts. Pages are
+// ordered by appointment_date and id, and the next page starts strictly
+// after the last row of the current one, so results stay stable while
+// appointments are inserted.
+func (p *PostgresRepository) findPage(ctx context.Context, w where, opts model.ListOptions) (*model.AppointmentPage, error) {
+	if !opts.From.IsZero() {
+		w.and("appointment_date >= ?", opts.From)
+	}
+	if !opts.To.IsZero() {
+		w.and("appointment_date < ?", opts.To)
+	}
+
+	conn := postgresConn(ctx, p.db)
+	var page model.AppointmentPage
+	err := conn.QueryRowContext(ctx, "SELECT count(*) FROM appointments"+w.String(), w.args...).Scan(&page.Total)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	order, after := "ASC", ">"
+	if opts.Descending() {
+		order, after = "DESC", "<"
+	}
+
+	if opts.Page != "" {
+		cursor, err := model.DecodePageToken(opts.Page)
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+
+		if _, err := primitive.ObjectIDFromHex(cursor.ID); err != nil {
+			return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+
+		w.and("(appointment_date, id) "+after+" (?, ?)", cursor.Date, cursor.ID)
+	}
+
+	size := opts.PageSize()
+	query := fmt.Sprintf("SELECT %s FROM appointments%s ORDER BY appointment_date %s, id %s LIMIT %d",
+		appointmentColumns, w.String(), order, order, size+1)
+	rows, err := conn.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	app := make([]model.Appointment, 0)
+	for rows.Next() {
+		a, err := scanAppointment(rows)
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+		app = append(app, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	if len(app) > size {
+		app = app[:size]
+		page.NextPage = model.EncodePageToken(app[size-1])
+	}
+	page.Appointments = app
+
+	return &page, nil
+}
+
+func (p *PostgresRepository) exists(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	err := postgresConn(ctx, p.db).QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM appointments WHERE id = $1)", id,
+	).Scan(&exists)
+	if err != nil {
+		return false, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return exists, nil
+}
+
+// versionMismatch tells why a conditional write on id matched nothing,
+// either the appointment is gone or it is at another version.
+func (p *PostgresRepository) versionMismatch(ctx context.Context, id string) error {
+	exists, err := p.exists(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return appErr.ErrNotFound
+	}
+
+	return appErr.ErrVersionMismatch
+}
+
+// stored returns app as it is written. The end date and status are always
+// stored, there are no legacy rows to derive them for.
+func stored(app model.Appointment) model.Appointment {
+	app.EndDate = app.End()
+	app.Status = app.State()
+
+	return app
+}
+
+// appointmentArgs returns the values of appointmentColumns for app.
+func appointmentArgs(app model.Appointment) []interface{} {
+	return []interface{}{
+		app.ID,
+		app.UserID,
+		app.SalonID,
+		app.ProfessionalID,
+		app.AppointmentDate,
+		app.EndDate,
+		app.ServiceType,
+		app.PriceCents,
+		app.Status,
+		app.Version,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAppointment(row scanner) (model.Appointment, error) {
+	var app model.Appointment
+	err := row.Scan(
+		&app.ID,
+		&app.UserID,
+		&app.SalonID,
+		&app.ProfessionalID,
+		&app.AppointmentDate,
+		&app.EndDate,
+		&app.ServiceType,
+		&app.PriceCents,
+		&app.Status,
+		&app.Version,
+	)
+	app.AppointmentDate = app.AppointmentDate.UTC()
+	app.EndDate = app.EndDate.UTC()
+
+	return app, err
+}
+
+// postgresError wraps err as ErrDatabase, or as ErrOverlappingAppointment
+// when the write was rejected by appointments_no_overlap.
+func postgresError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == exclusionViolation {
+		return errors.Wrap(appErr.ErrOverlappingAppointment, pqErr.Message)
+	}
+
+	return errors.Wrap(appErr.ErrDatabase, err.Error())
+}
+
+// placeholders returns the tuple of the n parameters following the first
+// offset ones, e.g. ($3, $4) for offset 2 and n 2.
+func placeholders(offset, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = "$" + strconv.Itoa(offset+i+1)
+	}
+
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+// where is the WHERE clause of a query, built a condition at a time.
+type where struct {
+	conds []string
+	args  []interface{}
+}
+
+// and adds cond, where every ? is bound to the next of args.
+func (w *where) and(cond string, args ...interface{}) {
+	for _, arg := range args {
+		w.args = append(w.args, arg)
+		cond = strings.Replace(cond, "?", "$"+strconv.Itoa(len(w.args)), 1)
+	}
+	w.conds = append(w.conds, cond)
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
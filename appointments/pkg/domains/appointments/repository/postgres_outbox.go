@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresOutbox stores the events in the outbox table, created by
+// PostgresRepository.Migrate. AddOutbox joins the transaction of the
+// repository, so both must share the same database.
+type PostgresOutbox struct {
+	db *sql.DB
+}
+
+func NewPostgresOutbox(db *sql.DB) *PostgresOutbox {
+	return &PostgresOutbox{db: db}
+}
+
+// AddOutbox stores the entry, an entry whose key is already stored is
+// ignored.
+func (o *PostgresOutbox) AddOutbox(ctx context.Context, entry model.OutboxEntry) error {
+	if entry.ID == "" {
+		entry.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := postgresConn(ctx, o.db).ExecContext(ctx, `INSERT INTO outbox
+		(id, key, type, payload, created_at, attempts, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (key) DO NOTHING`,
+		entry.ID, entry.Key, entry.Type, entry.Payload, entry.CreatedAt, entry.Attempts, entry.LastError)
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return nil
+}
+
+// PendingOutbox returns the oldest entries not relayed nor parked yet, in the
+// order they were stored. Postgres has no TTL index, so the entries relayed more than
+// SentOutboxTTL ago are purged first.
+func (o *PostgresOutbox) PendingOutbox(ctx context.Context, limit int) ([]model.OutboxEntry, error) {
+	conn := postgresConn(ctx, o.db)
+	_, err := conn.ExecContext(ctx, "DELETE FROM outbox WHERE sent_at < $1", time.Now().Add(-SentOutboxTTL))
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT id, key, type, payload, created_at, attempts, last_error
+		FROM outbox WHERE sent_at IS NULL AND parked_at IS NULL ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	var entries []model.OutboxEntry
+	for rows.Next() {
+		var entry model.OutboxEntry
+		err := rows.Scan(&entry.ID, &entry.Key, &entry.Type, &entry.Payload,
+			&entry.CreatedAt, &entry.Attempts, &entry.LastError)
+		if err != nil {
+			return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+		}
+		entry.CreatedAt = entry.CreatedAt.UTC()
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	return entries, nil
+}
+
+func (o *PostgresOutbox) MarkOutboxSent(ctx context.Context, id string) error {
+	return o.update(ctx, "UPDATE outbox SET sent_at = $2 WHERE id = $1", id, time.Now().UTC())
+}
+
+func (o *PostgresOutbox) MarkOutboxFailed(ctx context.Context, id string, reason string) error {
+	return o.update(ctx, "UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1", id, reason)
+}
+
+// ParkOutbox records the last failure of the entry and stops relaying it.
+func (o *PostgresOutbox) ParkOutbox(ctx context.Context, id string, reason string) error {
+	return o.update(ctx, "UPDATE outbox SET attempts = attempts + 1, last_error = $2, parked_at = now() WHERE id = $1",
+		id, reason)
+}
+
+func (o *PostgresOutbox) update(ctx context.Context, query, id string, arg interface{}) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return errors.Wrap(appErr.ErrInvalidID, err.Error())
+	}
+
+	result, err := postgresConn(ctx, o.db).ExecContext(ctx, query, id, arg)
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(appErr.ErrDatabase, err.Error())
+	}
+
+	if updated == 0 {
+		return appErr.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPostgres connects to the database in POSTGRES_TEST_URI and returns
+// a repository migrated into a throwaway schema, skipping the test when it
+// is unset.
+func newTestPostgres(t *testing.T) *PostgresRepository {
+	t.Helper()
+	uri := os.Getenv("POSTGRES_TEST_URI")
+	if uri == "" {
+		t.Skip("POSTGRES_TEST_URI not set, skipping postgres integration test")
+	}
+
+	ctx := context.Background()
+	admin, err := sql.Open("postgres", uri)
+	require.NoError(t, err)
+
+	schema := "appointments_test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	_, err = admin.ExecContext(ctx, "CREATE SCHEMA "+schema)
+	require.NoError(t, err)
+
+	dsn, err := url.Parse(uri)
+	require.NoError(t, err)
+	query := dsn.Query()
+	query.Set("search_path", schema)
+	dsn.RawQuery = query.Encode()
+
+	db, err := sql.Open("postgres", dsn.String())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+		_, _ = admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		_ = admin.Close()
+	})
+
+	repo := NewPostgresRepository(db)
+	require.NoError(t, repo.Migrate(ctx))
+
+	return repo
+}
+
+func TestPostgresRepository_Migrate(t *testing.T) {
+	repo := newTestPostgres(t)
+
+	require.NoError(t, repo.Migrate(context.Background()), "migrating twice is harmless")
+}
+
+func TestPostgresRepository_NoOverlap(t *testing.T) {
+	repo := newTestPostgres(t)
+	ctx := context.Background()
+
+	start := time.Date(2030, time.June, 23, 9, 0, 0, 0, time.UTC)
+	slot := model.Appointment{SalonID: 1, ProfessionalID: 3, AppointmentDate: start, EndDate: start.Add(time.Hour)}
+	_, err := repo.CreateAppointment(ctx, slot)
+	require.NoError(t, err)
+
+	overlap, err := repo.HasOverlap(ctx, model.Appointment{SalonID: 1, ProfessionalID: 3, AppointmentDate: start.Add(30 * time.Minute)})
+	require.NoError(t, err)
+	assert.True(t, overlap)
+
+	_, err = repo.CreateAppointment(ctx, model.Appointment{SalonID: 1, ProfessionalID: 3, AppointmentDate: start.Add(30 * time.Minute)})
+	assert.ErrorIs(t, err, appErr.ErrOverlappingAppointment, "the constraint double-checks HasOverlap")
+
+	_, err = repo.CreateAppointment(ctx, model.Appointment{SalonID: 1, ProfessionalID: 4, AppointmentDate: start})
+	assert.NoError(t, err)
+	_, err = repo.CreateAppointment(ctx, model.Appointment{SalonID: 1, ProfessionalID: 3, AppointmentDate: start.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	created, err := repo.CreateAppointments(ctx, []model.Appointment{
+		slot,
+		{SalonID: 1, ProfessionalID: 3, AppointmentDate: start.Add(2 * time.Hour)},
+		{SalonID: 1, ProfessionalID: 3, AppointmentDate: start.Add(2 * time.Hour)},
+	})
+	require.NoError(t, err)
+	require.Len(t, created, 1, "slots taken, before or in the same batch, are skipped")
+	assert.True(t, created[0].AppointmentDate.Equal(start.Add(2*time.Hour)))
+}
+
+func TestPostgresOutbox_WithTransaction(t *testing.T) {
+	repo := newTestPostgres(t)
+	outbox := NewPostgresOutbox(repo.db)
+	ctx := context.Background()
+	slot := model.Appointment{SalonID: 1, AppointmentDate: time.Date(2030, time.June, 23, 21, 0, 0, 0, time.UTC)}
+	entry := func(key string) model.OutboxEntry {
+		return model.OutboxEntry{Key: key, Type: "appointment.created", Payload: []byte(`{}`), CreatedAt: time.Now().UTC()}
+	}
+
+	errRollback := errors.New("rollback")
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateAppointment(ctx, slot); err != nil {
+			return err
+		}
+		if err := outbox.AddOutbox(ctx, entry("aborted")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	page, err := repo.FindAllAppointments(ctx, model.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Appointments)
+
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateAppointment(ctx, slot); err != nil {
+			return err
+		}
+		for _, key := range []string{"first", "second", "first"} {
+			if err := outbox.AddOutbox(ctx, entry(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	pending, err := outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2, "an entry whose key is stored is ignored")
+	assert.Equal(t, "first", pending[0].Key)
+
+	require.NoError(t, outbox.MarkOutboxFailed(ctx, pending[0].ID, "channel closed"))
+	require.NoError(t, outbox.MarkOutboxSent(ctx, pending[1].ID))
+	assert.ErrorIs(t, outbox.MarkOutboxSent(ctx, "62b65300e1d7eab1ea9a681d"), appErr.ErrNotFound)
+
+	pending, err = outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "channel closed", pending[0].LastError)
+
+	require.NoError(t, outbox.ParkOutbox(ctx, pending[0].ID, "channel closed"))
+	pending, err = outbox.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "parked entries are not relayed")
+}
+
+func Test_where(t *testing.T) {
+	var w where
+	assert.Empty(t, w.String())
+
+	w.and("salon_id = ?", 1)
+	w.and("status IN (?, ?)", model.StatusAvailable, model.StatusCancelled)
+	w.and("(appointment_date, id) > (?, ?)", time.Time{}, "62b65300e1d7eab1ea9a681d")
+
+	assert.Equal(t, " WHERE salon_id = $1 AND status IN ($2, $3) AND (appointment_date, id) > ($4, $5)", w.String())
+	assert.Len(t, w.args, 5)
+	assert.Equal(t, "($11, $12, $13)", placeholders(10, 3))
+}
+
+func Test_postgresError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "overlapping slot",
+			err:  &pq.Error{Code: exclusionViolation, Message: "conflicting key value violates exclusion constraint"},
+			want: appErr.ErrOverlappingAppointment,
+		},
+		{
+			name: "duplicate id",
+			err:  &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"},
+			want: appErr.ErrDatabase,
+		},
+		{
+			name: "connection lost",
+			err:  sql.ErrConnDone,
+			want: appErr.ErrDatabase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, postgresError(tt.err), tt.want)
+		})
+	}
+}
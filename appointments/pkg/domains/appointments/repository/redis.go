@@ -1,108 +1,54 @@
 package repository
 
 import (
-	"encoding/json"
-	"fmt"
-	"strconv"
 	"time"
 
-	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
 	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
 )
 
-const (
-	userID     = "user_"
-	salonID    = "salon_"
-	expiration = time.Hour
-)
-
-type RedisRepository struct {
+// RedisCache stores the entries of CachedRepository in Redis.
+type RedisCache struct {
 	client *redis.Client
 }
 
-func NewRedisRepository(c *redis.Client) *RedisRepository {
-	return &RedisRepository{
+func NewRedisCache(c *redis.Client) *RedisCache {
+	return &RedisCache{
 		client: c,
 	}
 }
 
-func (r *RedisRepository) CreateAppMemoryByID(app model.Appointment) error {
-	appMemory, err := json.Marshal(app)
-	if err != nil {
-		return err
+// Get returns the entry of key, a missing key is appErr.ErrNotFound.
+func (r *RedisCache) Get(key string) ([]byte, error) {
+	value, err := r.client.Get(key).Bytes()
+	if err == redis.Nil {
+		return nil, appErr.ErrNotFound
 	}
 
-	if re := r.client.Set(app.ID, appMemory, expiration); re.Err() != nil {
-		return re.Err()
-	}
-
-	return nil
-}
-
-func (r *RedisRepository) CreateAppMemoryByUserID(app []model.Appointment) error {
-	id := strconv.Itoa(app[0].UserID)
-
-	byteApp, err := json.Marshal(&app)
 	if err != nil {
-		return err
-	}
-
-	if err := r.client.Set(fmt.Sprintf("%v%s", userID, id), byteApp, expiration).Err(); err != nil {
-		return err
+		return nil, errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
 	}
 
-	return nil
+	return value, nil
 }
 
-func (r *RedisRepository) CreateAppMemoryBySalonID(app []model.Appointment) error {
-	id := strconv.Itoa(app[0].SalonID)
-
-	byteApp, err := json.Marshal(&app)
-	if err != nil {
-		return err
-	}
-
-	if err := r.client.Set(fmt.Sprintf("%v%s", salonID, id), byteApp, expiration).Err(); err != nil {
-		return err
+func (r *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(key, value, ttl).Err(); err != nil {
+		return errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
 	}
 
 	return nil
 }
 
-func (r *RedisRepository) FindAppByIDMemory(id string) (*model.Appointment, error) {
-	var app model.Appointment
-	byteApp, err := r.client.Get(id).Bytes()
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(byteApp, &app); err != nil {
-		return nil, err
-	}
-	return &app, nil
-}
-
-func (r *RedisRepository) FindAppByUserIDMemory(id int) ([]model.Appointment, error) {
-	var app []model.Appointment
-	byteApp, err := r.client.Get(fmt.Sprintf("%v%d", userID, id)).Bytes()
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(byteApp, &app); err != nil {
-		return nil, err
+func (r *RedisCache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
 	}
 
-	return app, nil
-}
-
-func (r *RedisRepository) FindAppBySalonIDMemory(id int) ([]model.Appointment, error) {
-	var app []model.Appointment
-	byteApp, err := r.client.Get(fmt.Sprintf("%v%d", salonID, id)).Bytes()
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(byteApp, &app); err != nil {
-		return nil, err
+	if err := r.client.Del(keys...).Err(); err != nil {
+		return errors.Wrap(appErr.ErrMemoryDatabase, err.Error())
 	}
 
-	return app, nil
+	return nil
 }
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRedisDB is the database the tests flush, REDIS_TEST_ADDR must point
+// to a server where it is disposable.
+const testRedisDB = 15
+
+// newTestRedis connects to the server in REDIS_TEST_ADDR and returns a
+// cache over an empty database, skipping the test when it is unset.
+func newTestRedis(t *testing.T) *RedisCache {
+	t.Helper()
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR not set, skipping redis integration test")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr, DB: testRedisDB})
+	require.NoError(t, client.FlushDB().Err())
+	t.Cleanup(func() {
+		_ = client.FlushDB().Err()
+		_ = client.Close()
+	})
+
+	return NewRedisCache(client)
+}
+
+func TestRedisIdempotency(t *testing.T) {
+	client := newTestRedis(t).client
+	store := NewRedisIdempotency(client)
+
+	_, done, err := store.ReserveKey("key", "fingerprint")
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	_, _, err = store.ReserveKey("key", "fingerprint")
+	assert.ErrorIs(t, err, appErr.ErrRequestInProgress)
+	_, _, err = store.ReserveKey("key", "another")
+	assert.ErrorIs(t, err, appErr.ErrIdempotencyKeyReused)
+
+	require.NoError(t, store.CompleteKey("key", "fingerprint", []byte(`{}`)))
+	response, done, err := store.ReserveKey("key", "fingerprint")
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []byte(`{}`), response)
+	_, _, err = store.ReserveKey("key", "another")
+	assert.ErrorIs(t, err, appErr.ErrIdempotencyKeyReused)
+
+	require.NoError(t, client.Set(idempotencyKey+"legacy", `{}`, IdempotencyTTL).Err())
+	response, done, err = store.ReserveKey("legacy", "fingerprint")
+	require.NoError(t, err, "responses stored without a fingerprint are still replayed")
+	assert.True(t, done)
+	assert.Equal(t, []byte(`{}`), response)
+}
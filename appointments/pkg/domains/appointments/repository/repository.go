@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
 )
@@ -14,35 +15,60 @@ type AppointmentRepositoryI interface {
 }
 
 type Querier interface {
-	FindAllAppointments(context.Context) ([]model.Appointment, error)
+	FindAllAppointments(context.Context, model.ListOptions) (*model.AppointmentPage, error)
 	FindAppointmentByID(context.Context, string) (*model.Appointment, error)
-	FindAppointmentByUserID(context.Context, int) ([]model.Appointment, error)
-	FindAppointmentBySalonID(context.Context, int) ([]model.Appointment, error)
-	AvaiableAppointment(context.Context) ([]model.Appointment, error)
+	FindAppointmentByUserID(context.Context, int, model.ListOptions) (*model.AppointmentPage, error)
+	FindAppointmentBySalonID(context.Context, int, model.ListOptions) (*model.AppointmentPage, error)
+	FindAppointmentByProfessionalID(context.Context, int, model.ListOptions) (*model.AppointmentPage, error)
+	AvaiableAppointment(context.Context, model.FindAvailable) (*model.AppointmentPage, error)
+	HasOverlap(context.Context, model.Appointment) (bool, error)
 }
 
 type Execer interface {
+	// WithTransaction runs fn in a transaction, every call made with the
+	// context given to fn is committed or aborted together.
+	WithTransaction(ctx context.Context, fn func(context.Context) error) error
 	CreateAppointment(context.Context, model.Appointment) (*model.Appointment, error)
+	CreateAppointments(context.Context, []model.Appointment) ([]model.Appointment, error)
+	// UpdateAppointment, DeleteAppointment and CancelAppointment only apply to
+	// the appointment at the version given, otherwise they fail with
+	// ErrVersionMismatch.
 	UpdateAppointment(context.Context, model.Appointment) (*model.Appointment, error)
-	DeleteAppointment(context.Context, string) error
+	DeleteAppointment(context.Context, string, int64) error
 	MakeAppointment(context.Context, string, int) (*model.Appointment, error)
-	CancelAppointment(context.Context, string, int) error
+	CancelAppointment(ctx context.Context, id string, user int, version int64) error
+	UpdateStatus(context.Context, string, model.Status, model.Status) (*model.Appointment, error)
 }
 
-type AppointmentMemoryI interface {
-	QuerieMemory
-
-	ExecerMemory
+// OutboxI stores the events to relay to the broker. AddOutbox joins the
+// transaction of its context, so an event is only stored with its change.
+// PendingOutbox returns neither the entries sent nor the ones parked.
+type OutboxI interface {
+	AddOutbox(context.Context, model.OutboxEntry) error
+	PendingOutbox(ctx context.Context, limit int) ([]model.OutboxEntry, error)
+	MarkOutboxSent(ctx context.Context, id string) error
+	MarkOutboxFailed(ctx context.Context, id string, reason string) error
+	ParkOutbox(ctx context.Context, id string, reason string) error
 }
 
-type QuerieMemory interface {
-	FindAppByIDMemory(string) (*model.Appointment, error)
-	FindAppByUserIDMemory(int) ([]model.Appointment, error)
-	FindAppBySalonIDMemory(int) ([]model.Appointment, error)
+// IdempotencyI records the responses of the requests sent with an
+// idempotency key, so that a request sent again gets the first response.
+// Every key is stored with the fingerprint of its request.
+type IdempotencyI interface {
+	// ReserveKey claims key for a new request. When key belongs to a
+	// completed request it returns its response and done; when it belongs
+	// to a request still in progress it fails with ErrRequestInProgress, and
+	// when it belongs to a request with another fingerprint with
+	// ErrIdempotencyKeyReused.
+	ReserveKey(key string, fingerprint string) (response []byte, done bool, err error)
+	CompleteKey(key string, fingerprint string, response []byte) error
+	ReleaseKey(key string) error
 }
 
-type ExecerMemory interface {
-	CreateAppMemoryByID(model.Appointment) error
-	CreateAppMemoryByUserID([]model.Appointment) error
-	CreateAppMemoryBySalonID([]model.Appointment) error
+// CacheI stores the encoded entries of CachedRepository. Get fails with
+// ErrNotFound when key is missing or expired.
+type CacheI interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
 }
@@ -0,0 +1,649 @@
+// Package repositorytest checks that an implementation of the repository
+// interfaces behaves the way the service relies on, so every storage
+// backend can be validated by the same suite.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// missingID is a well-formed ID no test ever stores, invalidID is not a
+// well-formed ID at all.
+const (
+	missingID = "62b65300e1d7eab1ea9a681d"
+	invalidID = "not-an-id"
+)
+
+// future is far enough ahead for the slots to stay available, and whole
+// seconds, so that it survives the precision of every backend.
+var future = time.Date(2030, time.June, 23, 9, 0, 0, 0, time.UTC)
+
+// slot returns an available slot of salon starting after offset from future.
+func slot(salon int, offset time.Duration) model.Appointment {
+	return model.Appointment{
+		SalonID:         salon,
+		AppointmentDate: future.Add(offset),
+		EndDate:         future.Add(offset + model.SlotDuration),
+		Status:          model.StatusAvailable,
+	}
+}
+
+// TestAppointmentRepository runs the suite against the repositories returned
+// by newRepository, which is called once per test and must return an empty
+// repository not shared with any other test.
+func TestAppointmentRepository(t *testing.T, newRepository func(t *testing.T) repository.AppointmentRepositoryI) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repository.AppointmentRepositoryI)
+	}{
+		{"CreateAppointment", testCreateAppointment},
+		{"CreateAppointments", testCreateAppointments},
+		{"FindAppointmentByID", testFindAppointmentByID},
+		{"InvalidID", testInvalidID},
+		{"UpdateAppointment", testUpdateAppointment},
+		{"DeleteAppointment", testDeleteAppointment},
+		{"MakeAppointment", testMakeAppointment},
+		{"MakeAppointmentConcurrent", testMakeAppointmentConcurrent},
+		{"CancelAppointment", testCancelAppointment},
+		{"UpdateStatus", testUpdateStatus},
+		{"FindAllAppointments", testFindAllAppointments},
+		{"FindByOwner", testFindByOwner},
+		{"AvaiableAppointment", testAvaiableAppointment},
+		{"HasOverlap", testHasOverlap},
+		{"WithTransaction", testWithTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+// assertSame asserts that got holds the fields of want, dates compared as
+// instants.
+func assertSame(t *testing.T, want, got model.Appointment) {
+	t.Helper()
+	assert.Equal(t, want.ID, got.ID)
+	assert.Equal(t, want.UserID, got.UserID)
+	assert.Equal(t, want.SalonID, got.SalonID)
+	assert.Equal(t, want.ProfessionalID, got.ProfessionalID)
+	assert.True(t, want.AppointmentDate.Equal(got.AppointmentDate), "appointment_date %s, got %s", want.AppointmentDate, got.AppointmentDate)
+	assert.True(t, want.EndDate.Equal(got.EndDate), "end_date %s, got %s", want.EndDate, got.EndDate)
+	assert.Equal(t, want.ServiceType, got.ServiceType)
+	assert.Equal(t, want.PriceCents, got.PriceCents)
+	assert.Equal(t, want.Status, got.Status)
+	assert.Equal(t, want.Version, got.Version)
+}
+
+func create(t *testing.T, repo repository.AppointmentRepositoryI, app model.Appointment) model.Appointment {
+	t.Helper()
+	created, err := repo.CreateAppointment(context.Background(), app)
+	require.NoError(t, err)
+
+	return *created
+}
+
+func find(t *testing.T, repo repository.AppointmentRepositoryI, id string) model.Appointment {
+	t.Helper()
+	app, err := repo.FindAppointmentByID(context.Background(), id)
+	require.NoError(t, err)
+
+	return *app
+}
+
+func testCreateAppointment(t *testing.T, repo repository.AppointmentRepositoryI) {
+	app := slot(1, 0)
+	app.ProfessionalID = 3
+	app.ServiceType = model.ServiceHaircut
+	app.PriceCents = 4500
+
+	created := create(t, repo, app)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, int64(1), created.Version, "new appointments are at version 1")
+
+	app.ID, app.Version = created.ID, 1
+	assertSame(t, app, created)
+	assertSame(t, app, find(t, repo, created.ID))
+
+	other := create(t, repo, slot(2, 0))
+	assert.NotEqual(t, created.ID, other.ID)
+}
+
+func testCreateAppointments(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	slots := []model.Appointment{slot(1, 0), slot(1, time.Hour), slot(2, 0)}
+
+	created, err := repo.CreateAppointments(ctx, slots)
+	require.NoError(t, err)
+	require.Len(t, created, len(slots))
+	for i, app := range created {
+		assert.NotEmpty(t, app.ID)
+		assert.Equal(t, int64(1), app.Version)
+		assertSame(t, app, find(t, repo, app.ID))
+		assert.True(t, slots[i].AppointmentDate.Equal(app.AppointmentDate))
+	}
+
+	created, err = repo.CreateAppointments(ctx, append(slots, slot(1, 2*time.Hour)))
+	require.NoError(t, err)
+	require.Len(t, created, 1, "slots already taken are skipped")
+	assert.True(t, future.Add(2*time.Hour).Equal(created[0].AppointmentDate))
+
+	// Slots overlapping a stored slot, or one before them in the batch, are
+	// skipped too, even when they start at another time.
+	long := slot(2, -time.Hour)
+	long.EndDate = future.Add(time.Minute)
+	professional := slot(1, 15*time.Minute)
+	professional.ProfessionalID = 3
+	created, err = repo.CreateAppointments(ctx, []model.Appointment{
+		slot(1, 15*time.Minute), long, slot(3, 0), slot(3, 15*time.Minute), professional,
+	})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	assert.Equal(t, 3, created[0].SalonID)
+	assert.True(t, future.Equal(created[0].AppointmentDate))
+	assert.Equal(t, 3, created[1].ProfessionalID, "another professional of the salon is free")
+
+	created, err = repo.CreateAppointments(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+}
+
+func testFindAppointmentByID(t *testing.T, repo repository.AppointmentRepositoryI) {
+	app, err := repo.FindAppointmentByID(context.Background(), missingID)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	assert.Nil(t, app)
+}
+
+func testInvalidID(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	app := slot(1, 0)
+	app.ID = invalidID
+
+	_, err := repo.FindAppointmentByID(ctx, invalidID)
+	assert.ErrorIs(t, err, appErr.ErrInvalidID, "FindAppointmentByID")
+	_, err = repo.UpdateAppointment(ctx, app)
+	assert.ErrorIs(t, err, appErr.ErrInvalidID, "UpdateAppointment")
+	assert.ErrorIs(t, repo.DeleteAppointment(ctx, invalidID, 1), appErr.ErrInvalidID, "DeleteAppointment")
+	_, err = repo.MakeAppointment(ctx, invalidID, 7)
+	assert.ErrorIs(t, err, appErr.ErrInvalidID, "MakeAppointment")
+	assert.ErrorIs(t, repo.CancelAppointment(ctx, invalidID, 7, 1), appErr.ErrInvalidID, "CancelAppointment")
+	_, err = repo.UpdateStatus(ctx, invalidID, model.StatusBooked, model.StatusConfirmed)
+	assert.ErrorIs(t, err, appErr.ErrInvalidID, "UpdateStatus")
+	_, err = repo.HasOverlap(ctx, app)
+	assert.ErrorIs(t, err, appErr.ErrInvalidID, "HasOverlap")
+}
+
+func testUpdateAppointment(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	app := slot(1, 0)
+	app.ServiceType = model.ServiceHaircut
+	app.PriceCents = 4500
+	stored := create(t, repo, app)
+
+	update := stored
+	update.AppointmentDate = future.Add(time.Hour)
+	update.EndDate = future.Add(2 * time.Hour)
+	update.ServiceType = ""
+	update.PriceCents = 0
+	updated, err := repo.UpdateAppointment(ctx, update)
+	require.NoError(t, err)
+
+	update.Version = 2
+	assertSame(t, update, *updated)
+	assertSame(t, update, find(t, repo, stored.ID))
+
+	_, err = repo.UpdateAppointment(ctx, stored)
+	assert.ErrorIs(t, err, appErr.ErrVersionMismatch, "stored is at version 1, the appointment at 2")
+
+	stored.ID = missingID
+	_, err = repo.UpdateAppointment(ctx, stored)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func testDeleteAppointment(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	stored := create(t, repo, slot(1, 0))
+
+	assert.ErrorIs(t, repo.DeleteAppointment(ctx, stored.ID, 2), appErr.ErrVersionMismatch)
+	require.NoError(t, repo.DeleteAppointment(ctx, stored.ID, 1))
+
+	_, err := repo.FindAppointmentByID(ctx, stored.ID)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	assert.ErrorIs(t, repo.DeleteAppointment(ctx, stored.ID, 1), appErr.ErrNotFound)
+}
+
+func testMakeAppointment(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	stored := create(t, repo, slot(1, 0))
+
+	booked, err := repo.MakeAppointment(ctx, stored.ID, 7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, booked.UserID)
+	assert.Equal(t, model.StatusBooked, booked.Status)
+	assert.Equal(t, int64(2), booked.Version)
+	assertSame(t, *booked, find(t, repo, stored.ID))
+
+	_, err = repo.MakeAppointment(ctx, stored.ID, 8)
+	assert.ErrorIs(t, err, appErr.ErrAlreadyBooked)
+
+	_, err = repo.MakeAppointment(ctx, missingID, 8)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+
+	require.NoError(t, repo.CancelAppointment(ctx, stored.ID, 7, booked.Version))
+	booked, err = repo.MakeAppointment(ctx, stored.ID, 8)
+	require.NoError(t, err, "cancelled slots can be booked again")
+	assert.Equal(t, 8, booked.UserID)
+}
+
+func testMakeAppointmentConcurrent(t *testing.T, repo repository.AppointmentRepositoryI) {
+	const bookers = 20
+	ctx := context.Background()
+	stored := create(t, repo, slot(1, 0))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		winner []int
+		booked int
+	)
+	wg.Add(bookers)
+	for i := 1; i <= bookers; i++ {
+		go func(user int) {
+			defer wg.Done()
+			_, err := repo.MakeAppointment(ctx, stored.ID, user)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				winner = append(winner, user)
+			case assert.ErrorIs(t, err, appErr.ErrAlreadyBooked):
+				booked++
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Len(t, winner, 1)
+	assert.Equal(t, bookers-1, booked)
+	assert.Equal(t, winner[0], find(t, repo, stored.ID).UserID)
+}
+
+func testCancelAppointment(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	stored := create(t, repo, slot(1, 0))
+	booked, err := repo.MakeAppointment(ctx, stored.ID, 7)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, repo.CancelAppointment(ctx, stored.ID, 8, booked.Version), appErr.ErrNotOwner,
+		"only the user who booked can cancel")
+	assert.ErrorIs(t, repo.CancelAppointment(ctx, stored.ID, 7, stored.Version), appErr.ErrVersionMismatch,
+		"stored is at version 1, the appointment at 2")
+	assertSame(t, *booked, find(t, repo, stored.ID))
+
+	require.NoError(t, repo.CancelAppointment(ctx, stored.ID, 7, booked.Version))
+	cancelled := find(t, repo, stored.ID)
+	assert.Equal(t, model.StatusCancelled, cancelled.Status)
+	assert.Equal(t, 7, cancelled.UserID)
+	assert.Equal(t, int64(3), cancelled.Version)
+
+	assert.ErrorIs(t, repo.CancelAppointment(ctx, stored.ID, 7, cancelled.Version), appErr.ErrInvalidTransition)
+	assert.ErrorIs(t, repo.CancelAppointment(ctx, missingID, 7, 1), appErr.ErrNotFound)
+}
+
+func testUpdateStatus(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	stored := create(t, repo, slot(1, 0))
+	_, err := repo.MakeAppointment(ctx, stored.ID, 7)
+	require.NoError(t, err)
+
+	confirmed, err := repo.UpdateStatus(ctx, stored.ID, model.StatusBooked, model.StatusConfirmed)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusConfirmed, confirmed.Status)
+	assert.Equal(t, 7, confirmed.UserID)
+	assert.Equal(t, int64(3), confirmed.Version)
+	assertSame(t, *confirmed, find(t, repo, stored.ID))
+
+	_, err = repo.UpdateStatus(ctx, stored.ID, model.StatusBooked, model.StatusNoShow)
+	assert.ErrorIs(t, err, appErr.ErrInvalidTransition, "the appointment is no longer booked")
+
+	_, err = repo.UpdateStatus(ctx, missingID, model.StatusBooked, model.StatusConfirmed)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func testFindAllAppointments(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	// Two slots at each hour, of different salons, so that pages break ties
+	// by ID.
+	for i := 0; i < 6; i++ {
+		create(t, repo, slot(i, time.Duration(i/2)*time.Hour))
+	}
+
+	for _, sort := range []string{model.SortDateAsc, model.SortDateDesc} {
+		opts := model.ListOptions{Limit: 4, Sort: sort}
+		var listed []model.Appointment
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 2, "6 appointments fit in 2 pages of 4")
+			page, err := repo.FindAllAppointments(ctx, opts)
+			require.NoError(t, err)
+			assert.Equal(t, int64(6), page.Total)
+			listed = append(listed, page.Appointments...)
+			if page.NextPage == "" {
+				break
+			}
+			opts.Page = page.NextPage
+		}
+
+		require.Len(t, listed, 6, sort)
+		seen := make(map[string]bool)
+		for i, app := range listed {
+			assert.False(t, seen[app.ID], "%s listed twice", app.ID)
+			seen[app.ID] = true
+			if i == 0 {
+				continue
+			}
+			if sort == model.SortDateAsc {
+				assert.False(t, app.AppointmentDate.Before(listed[i-1].AppointmentDate))
+			} else {
+				assert.False(t, app.AppointmentDate.After(listed[i-1].AppointmentDate))
+			}
+		}
+	}
+
+	page, err := repo.FindAllAppointments(ctx, model.ListOptions{From: future.Add(time.Hour), To: future.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total, "From is inclusive and To exclusive")
+	assert.Len(t, page.Appointments, 2)
+	assert.Empty(t, page.NextPage)
+
+	_, err = repo.FindAllAppointments(ctx, model.ListOptions{Page: "not a token"})
+	assert.ErrorIs(t, err, appErr.ErrInvalidQuery)
+}
+
+func testFindByOwner(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	for i, app := range []model.Appointment{slot(1, 0), slot(1, time.Hour), slot(2, 0)} {
+		app.ProfessionalID = i + 1
+		stored := create(t, repo, app)
+		if i < 2 {
+			_, err := repo.MakeAppointment(ctx, stored.ID, 7)
+			require.NoError(t, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		find  func() (*model.AppointmentPage, error)
+		total int64
+	}{
+		{
+			name: "by user",
+			find: func() (*model.AppointmentPage, error) {
+				return repo.FindAppointmentByUserID(ctx, 7, model.ListOptions{})
+			},
+			total: 2,
+		},
+		{
+			name: "by user, nothing booked",
+			find: func() (*model.AppointmentPage, error) {
+				return repo.FindAppointmentByUserID(ctx, 8, model.ListOptions{})
+			},
+			total: 0,
+		},
+		{
+			name: "by salon",
+			find: func() (*model.AppointmentPage, error) {
+				return repo.FindAppointmentBySalonID(ctx, 1, model.ListOptions{})
+			},
+			total: 2,
+		},
+		{
+			name: "by professional",
+			find: func() (*model.AppointmentPage, error) {
+				return repo.FindAppointmentByProfessionalID(ctx, 3, model.ListOptions{})
+			},
+			total: 1,
+		},
+		{
+			name: "by salon, limited",
+			find: func() (*model.AppointmentPage, error) {
+				return repo.FindAppointmentBySalonID(ctx, 1, model.ListOptions{Limit: 1})
+			},
+			total: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.find()
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, page.Total)
+			assert.NotNil(t, page.Appointments, "an empty page holds an empty list")
+			if int64(len(page.Appointments)) < tt.total {
+				assert.NotEmpty(t, page.NextPage)
+			}
+		})
+	}
+}
+
+func testAvaiableAppointment(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	haircut := slot(1, 0)
+	haircut.ServiceType = model.ServiceHaircut
+	manicure := slot(1, time.Hour)
+	manicure.ServiceType = model.ServiceManicure
+	past := slot(1, 0)
+	past.AppointmentDate = time.Date(2020, time.June, 23, 9, 0, 0, 0, time.UTC)
+	past.EndDate = past.AppointmentDate.Add(model.SlotDuration)
+
+	want := map[string]model.Appointment{}
+	for _, app := range []model.Appointment{haircut, manicure, slot(2, 0)} {
+		stored := create(t, repo, app)
+		want[stored.ID] = stored
+	}
+	create(t, repo, past)
+	booked := create(t, repo, slot(1, 2*time.Hour))
+	_, err := repo.MakeAppointment(ctx, booked.ID, 7)
+	require.NoError(t, err)
+	cancelled := create(t, repo, slot(1, 3*time.Hour))
+	_, err = repo.MakeAppointment(ctx, cancelled.ID, 7)
+	require.NoError(t, err)
+	require.NoError(t, repo.CancelAppointment(ctx, cancelled.ID, 7, 2))
+	cancelled = find(t, repo, cancelled.ID)
+	want[cancelled.ID] = cancelled
+
+	tests := []struct {
+		name string
+		find model.FindAvailable
+		want []string
+	}{
+		{
+			name: "available and cancelled slots yet to start",
+			find: model.FindAvailable{},
+			want: []string{string(model.ServiceHaircut), string(model.ServiceManicure), "", ""},
+		},
+		{
+			name: "by salon",
+			find: model.FindAvailable{SalonIDs: []int{2}},
+			want: []string{""},
+		},
+		{
+			name: "by service type",
+			find: model.FindAvailable{SalonIDs: []int{1, 2}, ServiceTypes: []model.ServiceType{model.ServiceManicure}},
+			want: []string{string(model.ServiceManicure)},
+		},
+		{
+			name: "before a date",
+			find: model.FindAvailable{ListOptions: model.ListOptions{To: future.Add(time.Minute)}},
+			want: []string{string(model.ServiceHaircut), ""},
+		},
+		{
+			name: "nothing matches",
+			find: model.FindAvailable{SalonIDs: []int{3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.AvaiableAppointment(ctx, tt.find)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.want)), page.Total)
+
+			var types []string
+			for i, app := range page.Appointments {
+				stored, ok := want[app.ID]
+				if assert.True(t, ok, "%s is not available", app.ID) {
+					assertSame(t, stored, app)
+				}
+				if i > 0 {
+					assert.False(t, app.AppointmentDate.Before(page.Appointments[i-1].AppointmentDate), "listed by date")
+				}
+				types = append(types, string(app.ServiceType))
+			}
+			assert.ElementsMatch(t, tt.want, types)
+		})
+	}
+}
+
+func testHasOverlap(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	app := slot(1, 0)
+	app.ProfessionalID = 3
+	app.EndDate = future.Add(time.Hour)
+	stored := create(t, repo, app)
+
+	at := func(salon, professional int, offset time.Duration) model.Appointment {
+		app := slot(salon, offset)
+		app.ProfessionalID = professional
+		return app
+	}
+
+	tests := []struct {
+		name    string
+		app     model.Appointment
+		overlap bool
+	}{
+		{name: "starts during the slot", app: at(1, 3, 30*time.Minute), overlap: true},
+		{name: "ends during the slot", app: at(1, 3, -15*time.Minute), overlap: true},
+		{name: "ends when the slot starts", app: at(1, 3, -model.SlotDuration)},
+		{name: "starts when the slot ends", app: at(1, 3, time.Hour)},
+		{name: "another professional", app: at(1, 4, 0)},
+		{name: "no professional", app: at(1, 0, 0)},
+		{name: "another salon", app: at(2, 3, 0)},
+		{name: "the slot itself", app: stored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlap, err := repo.HasOverlap(ctx, tt.app)
+			require.NoError(t, err)
+			assert.Equal(t, tt.overlap, overlap)
+		})
+	}
+}
+
+func testWithTransaction(t *testing.T, repo repository.AppointmentRepositoryI) {
+	ctx := context.Background()
+	stored := create(t, repo, slot(1, 0))
+
+	errRollback := errors.New("rollback")
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateAppointment(ctx, slot(2, 0)); err != nil {
+			return err
+		}
+		if _, err := repo.MakeAppointment(ctx, stored.ID, 7); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	assertSame(t, stored, find(t, repo, stored.ID))
+	page, err := repo.FindAllAppointments(ctx, model.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total, "the slot created in the transaction is gone")
+
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.CreateAppointment(ctx, slot(2, 0)); err != nil {
+			return err
+		}
+		_, err := repo.MakeAppointment(ctx, stored.ID, 7)
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, find(t, repo, stored.ID).UserID)
+	page, err = repo.FindAllAppointments(ctx, model.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+}
+
+// TestCache runs the suite against the caches returned by newCache, which
+// is called once per test and must return an empty cache not shared with any
+// other test.
+func TestCache(t *testing.T, newCache func(t *testing.T) repository.CacheI) {
+	t.Run("GetSet", func(t *testing.T) {
+		cache := newCache(t)
+		_, err := cache.Get("user_1")
+		assert.ErrorIs(t, err, appErr.ErrNotFound, "a miss is an error")
+
+		value := []byte(`{"id":"62b65300e1d7eab1ea9a681d"}`)
+		require.NoError(t, cache.Set("user_1", value, time.Hour))
+		got, err := cache.Get("user_1")
+		require.NoError(t, err)
+		assert.Equal(t, value, got)
+
+		value[0] = '['
+		got, err = cache.Get("user_1")
+		require.NoError(t, err)
+		assert.Equal(t, byte('{'), got[0], "the value is stored as a copy")
+
+		require.NoError(t, cache.Set("user_1", []byte(`{}`), time.Hour))
+		got, err = cache.Get("user_1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`{}`), got, "a set replaces the entry")
+
+		_, err = cache.Get("salon_1")
+		assert.ErrorIs(t, err, appErr.ErrNotFound, "other keys are cached apart")
+	})
+
+	t.Run("EmptyValue", func(t *testing.T) {
+		cache := newCache(t)
+		require.NoError(t, cache.Set(missingID, []byte{}, time.Hour))
+		got, err := cache.Get(missingID)
+		require.NoError(t, err, "an empty entry is not a miss")
+		assert.Empty(t, got)
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		cache := newCache(t)
+		require.NoError(t, cache.Set("user_1", []byte(`{}`), 50*time.Millisecond))
+		time.Sleep(100 * time.Millisecond)
+		_, err := cache.Get("user_1")
+		assert.ErrorIs(t, err, appErr.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		cache := newCache(t)
+		for _, key := range []string{"user_1", "salon_1", "professional_1"} {
+			require.NoError(t, cache.Set(key, []byte(`{}`), time.Hour))
+		}
+
+		require.NoError(t, cache.Delete("user_1", "salon_1"))
+		for _, key := range []string{"user_1", "salon_1"} {
+			_, err := cache.Get(key)
+			assert.ErrorIs(t, err, appErr.ErrNotFound, key)
+		}
+		_, err := cache.Get("professional_1")
+		assert.NoError(t, err)
+
+		assert.NoError(t, cache.Delete("user_1"), "deleting a missing entry is harmless")
+		assert.NoError(t, cache.Delete(), "deleting no entry is harmless")
+	})
+}
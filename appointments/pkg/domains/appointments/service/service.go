@@ -2,43 +2,59 @@ package service
 
 import (
 	"context"
+	"time"
 
 	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/event"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/log"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/pkg/errors"
 )
 
 //go:generate mockgen -destination service_mock.go -package=service -source=service.go
 type AppointmentServiceI interface {
 	CreateAppointment(context.Context, model.UpsertAppointment) (*model.AppResponse, error)
+	GenerateAppointments(context.Context, model.SlotTemplate) (*model.GenerateResponse, error)
 	UpdateAppointment(context.Context, model.UpsertAppointment) (*model.AppResponse, error)
+	PatchAppointment(context.Context, model.PatchAppointment) (*model.AppResponse, error)
 	MakeAppointment(context.Context, model.MakeAppointment) (*model.AppResponse, error)
-	CancelAppointment(context.Context, model.MakeAppointment) error
-	FindAllAppointments(context.Context) ([]model.AppResponse, error)
-	FindAvailableAppointments(context.Context) ([]model.AppResponse, error)
+	CancelAppointment(context.Context, model.CancelAppointment) error
+	FindAllAppointments(context.Context, model.ListOptions) (*model.AppPageResponse, error)
+	FindAvailableAppointments(context.Context, model.FindAvailable) (*model.AppPageResponse, error)
 	FindAppByID(context.Context, model.FindAppointmentsByIDRequest) (*model.AppResponse, error)
-	FindAppByUserID(context.Context, model.FindAppByUser) ([]model.AppResponse, error)
-	FindAppBySalonID(context.Context, model.FindAppBySalon) ([]model.AppResponse, error)
+	FindAppByUserID(context.Context, model.FindAppByUser) (*model.AppPageResponse, error)
+	FindAppBySalonID(context.Context, model.FindAppBySalon) (*model.AppPageResponse, error)
+	FindAppByProfessionalID(context.Context, model.FindAppByProfessional) (*model.AppPageResponse, error)
 	DeleteApp(context.Context, model.DeleteAppointment) error
+	ChangeStatus(context.Context, model.ChangeStatus) (*model.AppResponse, error)
 }
 
+// now is the clock slots are checked against, replaced in tests.
+var now = time.Now
+
 type Service struct {
 	repository repository.AppointmentRepositoryI
-	memory     repository.AppointmentMemoryI
 	log        log.AppointmentLogI
+	publisher  event.Publisher
 }
 
+// NewService returns the appointment service, events are discarded when p
+// is nil.
 func NewService(l log.AppointmentLogI, r repository.AppointmentRepositoryI,
-	m repository.AppointmentMemoryI) (*Service, error) {
+	p event.Publisher) (*Service, error) {
 	if r == nil {
 		return nil, appErr.ErrEmptyRepository
 	}
 
+	if p == nil {
+		p = event.Discard
+	}
+
 	return &Service{
 		log:        l,
 		repository: r,
-		memory:     m,
+		publisher:  p,
 	}, nil
 }
 
@@ -49,7 +65,19 @@ func (s *Service) CreateAppointment(ctx context.Context, app model.UpsertAppoint
 	)
 
 	// _ = s.log.LogWithTime(&app)
-	if appPersistence, err = s.repository.CreateAppointment(ctx, model.NewAppointment(app)); err != nil {
+	create := model.NewAppointment(app)
+	if err = s.checkSlot(ctx, create, true); err != nil {
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	err = s.repository.WithTransaction(ctx, func(ctx context.Context) error {
+		if appPersistence, err = s.repository.CreateAppointment(ctx, create); err != nil {
+			return err
+		}
+		return s.publish(ctx, event.TypeCreated, *appPersistence)
+	})
+	if err != nil {
 		_ = s.log.LogWithTime(err)
 		return nil, err
 	}
@@ -58,121 +86,324 @@ func (s *Service) CreateAppointment(ctx context.Context, app model.UpsertAppoint
 	return &appResponse, nil
 }
 
+// GenerateAppointments publishes every slot described by the template. Slots
+// that already started, or that overlap a stored one, are skipped, so the
+// same template can be sent again.
+func (s *Service) GenerateAppointments(ctx context.Context, template model.SlotTemplate) (*model.GenerateResponse, error) {
+	slots, err := template.Slots()
+	if err != nil {
+		err = errors.Wrap(appErr.ErrInvalidBody, err.Error())
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	upcoming := make([]model.Appointment, 0, len(slots))
+	for _, slot := range slots {
+		if !past(slot) {
+			upcoming = append(upcoming, slot)
+		}
+	}
+
+	var created []model.Appointment
+	err = s.repository.WithTransaction(ctx, func(ctx context.Context) error {
+		if created, err = s.repository.CreateAppointments(ctx, upcoming); err != nil {
+			return err
+		}
+		return s.publish(ctx, event.TypeCreated, created...)
+	})
+	if err != nil {
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	return &model.GenerateResponse{
+		Created: len(created),
+		Skipped: len(slots) - len(created),
+	}, nil
+}
+
+// UpdateAppointment replaces the stored appointment. One that is missing is
+// left for the repository to report, any other read error fails the update,
+// as the checks of update need the stored appointment.
 func (s *Service) UpdateAppointment(ctx context.Context, app model.UpsertAppointment) (*model.AppResponse, error) {
+	old, err := s.storedAppointment(ctx, app.ID)
+	if err != nil && !errors.Is(err, appErr.ErrNotFound) {
+		return nil, err
+	}
+
+	return s.update(ctx, old, app)
+}
+
+// PatchAppointment merges the patch into the stored appointment and writes
+// it the same way as UpdateAppointment.
+func (s *Service) PatchAppointment(ctx context.Context, patch model.PatchAppointment) (*model.AppResponse, error) {
+	old, err := s.storedAppointment(ctx, patch.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := patch.Apply(model.NewUpsertAppointment(old))
+	if err != nil {
+		err = errors.Wrap(appErr.ErrInvalidBody, err.Error())
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	return s.update(ctx, old, app)
+}
+
+// update writes app over old, the appointment stored before the write.
+func (s *Service) update(ctx context.Context, old model.Appointment, app model.UpsertAppointment) (*model.AppResponse, error) {
 	var (
 		appUpdate *model.Appointment
 		err       error
 	)
-	if appUpdate, err = s.repository.UpdateAppointment(ctx, model.NewAppointment(app)); err != nil {
+	update := model.NewAppointment(app)
+	if !old.AppointmentDate.IsZero() {
+		// Booking and cancelling go through MakeAppointment and
+		// CancelAppointment, an update keeps the booking where it is.
+		if update.UserID != old.UserID {
+			err = errors.Wrap(appErr.ErrInvalidBody, "user_id is changed by booking or cancelling the appointment")
+			_ = s.log.LogWithTime(err)
+			return nil, err
+		}
+		update.Status = old.State()
+	}
+	update.Version = version(update.Version, old)
+
+	moved := old.AppointmentDate.IsZero() || !old.AppointmentDate.Equal(update.AppointmentDate) ||
+		!old.End().Equal(update.End())
+	if err = s.checkSlot(ctx, update, moved); err != nil {
 		_ = s.log.LogWithTime(err)
 		return nil, err
 	}
+
+	err = s.repository.WithTransaction(ctx, func(ctx context.Context) error {
+		if appUpdate, err = s.repository.UpdateAppointment(ctx, update); err != nil {
+			return err
+		}
+		return s.publish(ctx, event.TypeUpdated, *appUpdate)
+	})
+	if err != nil {
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
 	appReponse := model.NewAppResponse(*appUpdate)
 	return &appReponse, nil
 }
 
-func (s *Service) FindAllAppointments(ctx context.Context) ([]model.AppResponse, error) {
-	findAll, err := s.repository.FindAllAppointments(ctx)
+func (s *Service) FindAllAppointments(ctx context.Context, opts model.ListOptions) (*model.AppPageResponse, error) {
+	findAll, err := s.repository.FindAllAppointments(ctx, opts)
 	if err != nil {
 		_ = s.log.LogWithTime(err)
 		return nil, err
 	}
-	findAllResponse := model.NewAppResponseSlice(findAll)
+	findAllResponse := model.NewAppPageResponse(*findAll)
 
-	return findAllResponse, nil
+	return &findAllResponse, nil
 }
 
-func (s *Service) FindAvailableAppointments(ctx context.Context) ([]model.AppResponse, error) {
-	app, err := s.repository.AvaiableAppointment(ctx)
+func (s *Service) FindAvailableAppointments(ctx context.Context, find model.FindAvailable) (*model.AppPageResponse, error) {
+	app, err := s.repository.AvaiableAppointment(ctx, find)
 	if err != nil {
 		_ = s.log.LogWithTime(err)
 		return nil, err
 	}
 
-	avaiableResponse := model.NewAppResponseSlice(app)
-	return avaiableResponse, nil
+	avaiableResponse := model.NewAppPageResponse(*app)
+	return &avaiableResponse, nil
 }
 
 func (s *Service) FindAppByID(ctx context.Context, app model.FindAppointmentsByIDRequest) (*model.AppResponse, error) {
-	findByID, err := s.memory.FindAppByIDMemory(app.ID)
+	findByID, err := s.repository.FindAppointmentByID(ctx, app.ID)
 	if err != nil {
 		_ = s.log.LogWithTime(err)
-		if findByID, err = s.repository.FindAppointmentByID(ctx, app.ID); err != nil {
-			_ = s.log.LogWithTime(err)
-			return nil, err
-		}
-
-		if err = s.memory.CreateAppMemoryByID(*findByID); err != nil {
-			_ = s.log.LogWithTime(err)
-		}
+		return nil, err
 	}
 
 	findByIDResponse := model.NewAppResponse(*findByID)
 	return &findByIDResponse, nil
 }
 
-func (s *Service) FindAppByUserID(ctx context.Context, id model.FindAppByUser) ([]model.AppResponse, error) {
-	app, err := s.memory.FindAppByUserIDMemory(id.ID)
+func (s *Service) FindAppByUserID(ctx context.Context, id model.FindAppByUser) (*model.AppPageResponse, error) {
+	app, err := s.repository.FindAppointmentByUserID(ctx, id.ID, id.ListOptions)
 	if err != nil {
 		_ = s.log.LogWithTime(err)
-		app, err := s.repository.FindAppointmentByUserID(ctx, id.ID)
-		if err != nil {
-			_ = s.log.LogWithTime(err)
-			return nil, err
-		}
-
-		if err = s.memory.CreateAppMemoryByUserID(app); err != nil {
-			_ = s.log.LogWithTime(err)
-		}
-		appResponse := model.NewAppResponseSlice(app)
-		return appResponse, nil
+		return nil, err
 	}
-	appResponse := model.NewAppResponseSlice(app)
-	return appResponse, nil
+
+	appResponse := model.NewAppPageResponse(*app)
+	return &appResponse, nil
 }
 
-func (s *Service) FindAppBySalonID(ctx context.Context, id model.FindAppBySalon) ([]model.AppResponse, error) {
-	app, err := s.memory.FindAppBySalonIDMemory(id.ID)
+func (s *Service) FindAppBySalonID(ctx context.Context, id model.FindAppBySalon) (*model.AppPageResponse, error) {
+	app, err := s.repository.FindAppointmentBySalonID(ctx, id.ID, id.ListOptions)
 	if err != nil {
 		_ = s.log.LogWithTime(err)
-		app, err := s.repository.FindAppointmentBySalonID(ctx, id.ID)
-		if err != nil {
-			_ = s.log.LogWithTime(err)
-			return nil, err
-		}
+		return nil, err
+	}
 
-		if err = s.memory.CreateAppMemoryBySalonID(app); err != nil {
-			_ = s.log.LogWithTime(err)
-		}
-		appResponse := model.NewAppResponseSlice(app)
-		return appResponse, nil
+	appResponse := model.NewAppPageResponse(*app)
+	return &appResponse, nil
+}
+
+func (s *Service) FindAppByProfessionalID(ctx context.Context, id model.FindAppByProfessional) (*model.AppPageResponse, error) {
+	app, err := s.repository.FindAppointmentByProfessionalID(ctx, id.ID, id.ListOptions)
+	if err != nil {
+		_ = s.log.LogWithTime(err)
+		return nil, err
 	}
-	appResponse := model.NewAppResponseSlice(app)
-	return appResponse, nil
+
+	appResponse := model.NewAppPageResponse(*app)
+	return &appResponse, nil
 }
 
 func (s *Service) MakeAppointment(ctx context.Context, make model.MakeAppointment) (*model.AppResponse, error) {
-	app, err := s.repository.MakeAppointment(ctx, make.ID, make.UserID)
+	var app *model.Appointment
+	err := s.repository.WithTransaction(ctx, func(ctx context.Context) (err error) {
+		if app, err = s.repository.MakeAppointment(ctx, make.ID, make.UserID); err != nil {
+			return err
+		}
+		return s.publish(ctx, event.TypeBooked, *app)
+	})
 	if err != nil {
 		_ = s.log.LogWithTime(err)
 		return nil, err
 	}
+
 	appResponse := model.NewAppResponse(*app)
 	return &appResponse, nil
 }
 
 func (s *Service) DeleteApp(ctx context.Context, app model.DeleteAppointment) error {
-	if err := s.repository.DeleteAppointment(ctx, app.ID); err != nil {
+	old, _ := s.storedAppointment(ctx, app.ID)
+	err := s.repository.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteAppointment(ctx, app.ID, version(app.Version, old)); err != nil {
+			return err
+		}
+		return s.publish(ctx, event.TypeDeleted, old)
+	})
+	if err != nil {
+		_ = s.log.LogWithTime(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) CancelAppointment(ctx context.Context, app model.CancelAppointment) error {
+	old, err := s.storedAppointment(ctx, app.ID)
+	if err == nil && !old.State().CanTransition(model.StatusCancelled) {
+		err := errors.Wrapf(appErr.ErrInvalidTransition, "cannot cancel a %s appointment", old.State())
+		_ = s.log.LogWithTime(err)
+		return err
+	}
+
+	old.UserID = app.UserID
+	err = s.repository.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repository.CancelAppointment(ctx, app.ID, app.UserID, version(app.Version, old)); err != nil {
+			return err
+		}
+		cancelled := old
+		cancelled.Status = model.StatusCancelled
+		return s.publish(ctx, event.TypeCancelled, cancelled)
+	})
+	if err != nil {
 		_ = s.log.LogWithTime(err)
 		return err
 	}
+
 	return nil
 }
 
-func (s *Service) CancelAppointment(ctx context.Context, app model.MakeAppointment) error {
-	if err := s.repository.CancelAppointment(ctx, app.ID, app.UserID); err != nil {
+func (s *Service) ChangeStatus(ctx context.Context, change model.ChangeStatus) (*model.AppResponse, error) {
+	app, err := s.repository.FindAppointmentByID(ctx, change.ID)
+	if err != nil {
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	from := app.State()
+	if !from.CanTransition(change.Status) {
+		err := errors.Wrapf(appErr.ErrInvalidTransition, "%s -> %s", from, change.Status)
+		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	err = s.repository.WithTransaction(ctx, func(ctx context.Context) error {
+		if app, err = s.repository.UpdateStatus(ctx, change.ID, from, change.Status); err != nil {
+			return err
+		}
+		return s.publish(ctx, event.StatusType(change.Status), *app)
+	})
+	if err != nil {
 		_ = s.log.LogWithTime(err)
+		return nil, err
+	}
+
+	appResponse := model.NewAppResponse(*app)
+	return &appResponse, nil
+}
+
+// publish emits an event of type t for every appointment. It is called in
+// the transaction of the change, so with the outbox publisher the events
+// are stored, and later relayed, only if the change is.
+func (s *Service) publish(ctx context.Context, t event.Type, apps ...model.Appointment) error {
+	for _, app := range apps {
+		if err := s.publisher.Publish(ctx, event.New(t, app)); err != nil {
+			return errors.Wrapf(err, "publish %s", t)
+		}
+	}
+
+	return nil
+}
+
+// checkSlot rejects a slot that overlaps another slot of the same professional and,
+// when it is new or moved, a slot that does not start in the future.
+func (s *Service) checkSlot(ctx context.Context, app model.Appointment, moved bool) error {
+	if moved && past(app) {
+		return errors.Wrapf(appErr.ErrPastAppointment, "%s", app.AppointmentDate)
+	}
+
+	overlap, err := s.repository.HasOverlap(ctx, app)
+	if err != nil {
 		return err
 	}
+
+	if overlap {
+		return errors.Wrapf(appErr.ErrOverlappingAppointment, "salon %d professional %d at %s",
+			app.SalonID, app.ProfessionalID, app.AppointmentDate)
+	}
+
 	return nil
 }
+
+// past reports whether app already started, too late to be booked.
+func past(app model.Appointment) bool {
+	return !app.AppointmentDate.After(now())
+}
+
+// storedAppointment returns the persisted appointment before a write, for
+// the event of the write. When it cannot be read only the appointment ID is
+// known.
+func (s *Service) storedAppointment(ctx context.Context, id string) (model.Appointment, error) {
+	app, err := s.repository.FindAppointmentByID(ctx, id)
+	if err != nil {
+		_ = s.log.LogWithTime(err)
+		return model.Appointment{ID: id}, err
+	}
+
+	return *app, nil
+}
+
+// version returns the version a write of want applies to. AnyVersion stands
+// for old, the appointment the write was checked against, so it cannot land
+// over a change made in between.
+func version(want int64, old model.Appointment) int64 {
+	if want == model.AnyVersion {
+		return old.Version
+	}
+
+	return want
+}
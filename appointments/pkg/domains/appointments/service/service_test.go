@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
 	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/event"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/log"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
@@ -13,6 +16,13 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// fakeNow is a moment before every fixture date, so fixtures are in the future.
+var fakeNow = time.Date(2022, 05, 01, 12, 0, 0, 0, time.Local)
+
+func init() {
+	now = func() time.Time { return fakeNow }
+}
+
 var fakeUpsert = model.UpsertAppointment{
 	ID:              "629aac9c363519d9a9615369",
 	UserID:          1,
@@ -20,11 +30,21 @@ var fakeUpsert = model.UpsertAppointment{
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
 }
 
+var pastUpsert = model.UpsertAppointment{
+	ID:              "629aac9c363519d9a9615369",
+	UserID:          1,
+	SalonID:         1,
+	AppointmentDate: time.Date(2022, 04, 12, 18, 30, 25, 12, time.Local),
+}
+
 var fakeAppResponse = model.AppResponse{
 	ID:              "629aac9c363519d9a9615369",
 	UserID:          1,
 	SalonID:         1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	DurationMinutes: 30,
+	Status:          model.StatusBooked,
 }
 
 var fakeApp = model.Appointment{
@@ -32,6 +52,46 @@ var fakeApp = model.Appointment{
 	UserID:          1,
 	SalonID:         1,
 	AppointmentDate: time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local),
+	EndDate:         time.Date(2022, 05, 12, 18, 30, 25, 12, time.Local).Add(30 * time.Minute),
+	Status:          model.StatusBooked,
+}
+
+var fakeTemplate = model.SlotTemplate{
+	SalonID:     1,
+	TimeZone:    "UTC",
+	From:        "2022-07-04",
+	To:          "2022-07-04",
+	SlotMinutes: 60,
+	Days:        []model.OpeningHours{{Weekday: "monday", Open: "09:00", Close: "11:00"}},
+}
+
+var fakeSlots = []model.Appointment{
+	{
+		SalonID:         1,
+		AppointmentDate: time.Date(2022, 07, 04, 9, 0, 0, 0, time.UTC),
+		EndDate:         time.Date(2022, 07, 04, 10, 0, 0, 0, time.UTC),
+		Status:          model.StatusAvailable,
+	},
+	{
+		SalonID:         1,
+		AppointmentDate: time.Date(2022, 07, 04, 10, 0, 0, 0, time.UTC),
+		EndDate:         time.Date(2022, 07, 04, 11, 0, 0, 0, time.UTC),
+		Status:          model.StatusAvailable,
+	},
+}
+
+var pastApp = model.NewAppointment(pastUpsert)
+
+const fakeProfessionalID = 3
+
+var fakePage = model.AppointmentPage{
+	Appointments: []model.Appointment{fakeApp},
+	Total:        1,
+}
+
+var fakePageResponse = model.AppPageResponse{
+	Items: []model.AppResponse{fakeAppResponse},
+	Total: 1,
 }
 
 func TestNewService(t *testing.T) {
@@ -39,11 +99,12 @@ func TestNewService(t *testing.T) {
 	var ctrl *gomock.Controller
 	repo := repository.NewMockAppointmentRepositoryI(ctrl)
 
-	srv := Service{repository: repo, log: l}
+	publisher := event.NewMemoryPublisher()
+	srv := Service{repository: repo, log: l, publisher: event.Discard}
 	type args struct {
 		l          log.AppointmentLogI
 		repository repository.AppointmentRepositoryI
-		memory     repository.AppointmentMemoryI
+		publisher  event.Publisher
 	}
 	tests := []struct {
 		name string
@@ -59,6 +120,15 @@ func TestNewService(t *testing.T) {
 			},
 			want: &srv,
 		},
+		{
+			name: "success, created new service with a publisher",
+			args: args{
+				l:          l,
+				repository: repo,
+				publisher:  publisher,
+			},
+			want: &Service{repository: repo, log: l, publisher: publisher},
+		},
 		{
 			name: "fail, service cannot be created",
 			args: args{
@@ -70,13 +140,40 @@ func TestNewService(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewService(tt.args.l, tt.args.repository, tt.args.memory)
+			got, err := NewService(tt.args.l, tt.args.repository, tt.args.publisher)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+func TestService_publish(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	publisher := event.NewMemoryPublisher()
+	publisher.Fail(errors.New("outbox unavailable"))
+	l := log.NewMockAppointmentLogI(ctrl)
+	l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+	repo := repository.NewMockAppointmentRepositoryI(ctrl)
+	inTransaction(repo)
+	repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
+	repo.EXPECT().CreateAppointment(context.Background(), fakeApp).Return(&fakeApp, nil)
+	s := &Service{repository: repo, log: l, publisher: publisher}
+
+	got, err := s.CreateAppointment(context.Background(), fakeUpsert)
+	assert.Error(t, err, "the change is rolled back when its event cannot be stored")
+	assert.Nil(t, got)
+	assert.Empty(t, publisher.Events())
+}
+
+// inTransaction makes the repository run every transaction right away.
+func inTransaction(repo *repository.MockAppointmentRepositoryI) {
+	repo.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		}).AnyTimes()
+}
+
 func TestService_CreateAppointment(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	defer ctrl.Finish()
@@ -85,26 +182,56 @@ func TestService_CreateAppointment(t *testing.T) {
 		app model.UpsertAppointment
 	}
 	tests := []struct {
-		name string
-		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
-		args args
-		want *model.AppResponse
-		err  error
+		name   string
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		args   args
+		want   *model.AppResponse
+		err    error
+		events []event.Type
 	}{
 		{
-			name: "success, created new Appointment",
+			name:   "success, created new Appointment",
+			events: []event.Type{event.TypeCreated},
 			args: args{
 				ctx: context.Background(),
 				app: fakeUpsert,
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
 				repo.EXPECT().CreateAppointment(context.Background(), fakeApp).Return(&fakeApp, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
 				return repo, l
 			},
 			want: &fakeAppResponse,
 		},
+		{
+			name:   "success, created Appointment for a professional",
+			events: []event.Type{event.TypeCreated},
+			args: args{
+				ctx: context.Background(),
+				app: func() model.UpsertAppointment {
+					app := fakeUpsert
+					app.ProfessionalID = fakeProfessionalID
+					return app
+				}(),
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				app := fakeApp
+				app.ProfessionalID = fakeProfessionalID
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().HasOverlap(context.Background(), app).Return(false, nil)
+				repo.EXPECT().CreateAppointment(context.Background(), app).Return(&app, nil)
+				return repo, log.NewMockAppointmentLogI(ctrl)
+			},
+			want: func() *model.AppResponse {
+				resp := fakeAppResponse
+				resp.ProfessionalID = fakeProfessionalID
+				return &resp
+			}(),
+		},
 		{
 			name: "fail, don't was possible create a new Appointment",
 			args: args{
@@ -113,82 +240,573 @@ func TestService_CreateAppointment(t *testing.T) {
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().CreateAppointment(context.Background(), fakeApp).Return(nil, appErr.ErrDatabase)
+				inTransaction(repo)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
+				repo.EXPECT().CreateAppointment(context.Background(), fakeApp).Return(nil, appErr.ErrDatabase)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
+			},
+			want: nil,
+			err:  appErr.ErrDatabase,
+		},
+		{
+			name: "fail, Appointment in the past",
+			args: args{
+				ctx: context.Background(),
+				app: pastUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repository.NewMockAppointmentRepositoryI(ctrl), l
+			},
+			err: appErr.ErrPastAppointment,
+		},
+		{
+			name: "fail, Appointment at the current time",
+			args: args{
+				ctx: context.Background(),
+				app: model.UpsertAppointment{SalonID: 1, AppointmentDate: fakeNow},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repository.NewMockAppointmentRepositoryI(ctrl), l
+			},
+			err: appErr.ErrPastAppointment,
+		},
+		{
+			name: "fail, Appointment overlaps another slot",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(true, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrOverlappingAppointment,
+		},
+		{
+			name: "fail, don't was possible check overlapping slots",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, appErr.ErrDatabase)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrDatabase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
+			s := &Service{
+				repository: r,
+				log:        l,
+				publisher:  publisher,
+			}
+			got, err := s.CreateAppointment(tt.args.ctx, tt.args.app)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.events, publisher.Types())
+		})
+	}
+}
+
+func TestService_GenerateAppointments(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	type args struct {
+		ctx      context.Context
+		template model.SlotTemplate
+	}
+	tests := []struct {
+		name   string
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		args   args
+		want   *model.GenerateResponse
+		err    error
+		events []event.Type
+	}{
+		{
+			name:   "success, generated slots skipping the existing one",
+			events: []event.Type{event.TypeCreated},
+			args: args{
+				ctx:      context.Background(),
+				template: fakeTemplate,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				created := fakeSlots[1]
+				created.ID = "629aac9c363519d9a9615370"
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().CreateAppointments(context.Background(), fakeSlots).Return([]model.Appointment{created}, nil)
+				return repo, log.NewMockAppointmentLogI(ctrl)
+			},
+			want: &model.GenerateResponse{Created: 1, Skipped: 1},
+		},
+		{
+			name:   "success, slots that already started are skipped",
+			events: []event.Type{event.TypeCreated, event.TypeCreated},
+			args: args{
+				ctx: context.Background(),
+				template: func() model.SlotTemplate {
+					template := fakeTemplate
+					template.From, template.To = "2022-04-25", "2022-05-02"
+					return template
+				}(),
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				upcoming := make([]model.Appointment, 0, len(fakeSlots))
+				for _, slot := range fakeSlots {
+					slot.AppointmentDate = slot.AppointmentDate.AddDate(0, 0, -63)
+					slot.EndDate = slot.EndDate.AddDate(0, 0, -63)
+					upcoming = append(upcoming, slot)
+				}
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().CreateAppointments(context.Background(), upcoming).Return(upcoming, nil)
+				return repo, log.NewMockAppointmentLogI(ctrl)
+			},
+			want: &model.GenerateResponse{Created: 2, Skipped: 2},
+		},
+		{
+			name: "fail, invalid template",
+			args: args{
+				ctx: context.Background(),
+				template: model.SlotTemplate{
+					SalonID: 1, TimeZone: "UTC", From: "2022-07-04", To: "2022-07-04", SlotMinutes: 60,
+					Days: []model.OpeningHours{{Weekday: "monday", Open: "11:00", Close: "09:00"}},
+				},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repository.NewMockAppointmentRepositoryI(ctrl), l
+			},
+			err: appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, don't was possible insert the slots",
+			args: args{
+				ctx:      context.Background(),
+				template: fakeTemplate,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().CreateAppointments(context.Background(), fakeSlots).Return(nil, appErr.ErrDatabase)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrDatabase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
+			s := &Service{
+				repository: r,
+				log:        l,
+				publisher:  publisher,
+			}
+			got, err := s.GenerateAppointments(tt.args.ctx, tt.args.template)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.events, publisher.Types())
+		})
+	}
+}
+
+func TestService_UpdateAppointment(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	type args struct {
+		ctx context.Context
+		app model.UpsertAppointment
+	}
+	movedApp := fakeApp
+	movedApp.SalonID = 3
+	otherUserApp := fakeApp
+	otherUserApp.UserID = 2
+	anyVersion := fakeUpsert
+	anyVersion.Version = model.AnyVersion
+	storedApp := fakeApp
+	storedApp.Version = 4
+	storedResponse := model.NewAppResponse(storedApp)
+	tests := []struct {
+		name   string
+		args   args
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want   *model.AppResponse
+		err    error
+		events []event.Type
+	}{
+		{
+			name:   "success, updated Appointment",
+			events: []event.Type{event.TypeUpdated},
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(&fakeApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return repo, l
+			},
+			want: &fakeAppResponse,
+		},
+		{
+			name:   "success, updated Appointment at whatever version is stored",
+			events: []event.Type{event.TypeUpdated},
+			args: args{
+				ctx: context.Background(),
+				app: anyVersion,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&storedApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), storedApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), storedApp).Return(&storedApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return repo, l
+			},
+			want: &storedResponse,
+		},
+		{
+			name:   "success, Appointment moved from another salon",
+			events: []event.Type{event.TypeUpdated},
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&movedApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(&fakeApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return repo, l
+			},
+			want: &fakeAppResponse,
+		},
+		{
+			name:   "success, updated Appointment keeps the stored status",
+			events: []event.Type{event.TypeUpdated},
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				confirmedApp := fakeApp
+				confirmedApp.Status = model.StatusConfirmed
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&confirmedApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), confirmedApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), confirmedApp).Return(&fakeApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return repo, l
+			},
+			want: &fakeAppResponse,
+		},
+		{
+			name: "fail, update books the Appointment for another user",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&otherUserApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, don't was possible update Appointment",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(nil, appErr.ErrDatabase)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrDatabase,
+		},
+		{
+			name: "fail, cannot read the stored Appointment",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrDatabase)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrDatabase,
+		},
+		{
+			name: "fail, don't found Appointmemnt",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(nil, appErr.ErrNotFound)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil).Times(2)
+				return repo, l
+			},
+			err: appErr.ErrNotFound,
+		},
+		{
+			name:   "success, past Appointment that is not moved",
+			events: []event.Type{event.TypeUpdated},
+			args: args{
+				ctx: context.Background(),
+				app: pastUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), pastApp.ID).Return(&pastApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), pastApp).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), pastApp).Return(&pastApp, nil)
+				return repo, log.NewMockAppointmentLogI(ctrl)
+			},
+			want: func() *model.AppResponse {
+				resp := model.NewAppResponse(pastApp)
+				return &resp
+			}(),
+		},
+		{
+			name: "fail, Appointment moved to the past",
+			args: args{
+				ctx: context.Background(),
+				app: pastUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrPastAppointment,
+		},
+		{
+			name: "fail, Appointment moved over another slot",
+			args: args{
+				ctx: context.Background(),
+				app: fakeUpsert,
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&pastApp, nil)
+				repo.EXPECT().HasOverlap(context.Background(), fakeApp).Return(true, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrOverlappingAppointment,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
+			s := &Service{
+				repository: r,
+				log:        l,
+				publisher:  publisher,
+			}
+			got, err := s.UpdateAppointment(tt.args.ctx, tt.args.app)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.events, publisher.Types())
+		})
+	}
+}
+
+func TestService_PatchAppointment(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	stored := fakeApp
+	stored.AppointmentDate = fakeApp.AppointmentDate.UTC()
+	stored.EndDate = fakeApp.EndDate.UTC()
+	stored.Version = 2
+	moved := stored
+	moved.SalonID = 2
+	tests := []struct {
+		name   string
+		fields map[string]json.RawMessage
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want   *model.AppResponse
+		err    error
+		events []event.Type
+	}{
+		{
+			name:   "success, changed only the salon",
+			fields: map[string]json.RawMessage{"salon_id": json.RawMessage(`2`)},
+			events: []event.Type{event.TypeUpdated},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				updated := moved
+				updated.Version = 3
+				repo.EXPECT().FindAppointmentByID(context.Background(), stored.ID).Return(&stored, nil)
+				repo.EXPECT().HasOverlap(context.Background(), moved).Return(false, nil)
+				repo.EXPECT().UpdateAppointment(context.Background(), moved).Return(&updated, nil)
+				return repo, log.NewMockAppointmentLogI(ctrl)
+			},
+			want: func() *model.AppResponse {
+				updated := moved
+				updated.Version = 3
+				response := model.NewAppResponse(updated)
+				return &response
+			}(),
+		},
+		{
+			name:   "fail, rescheduled to the past",
+			fields: map[string]json.RawMessage{"appointment_date": json.RawMessage(`"2020-05-13T10:00:00Z"`)},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByID(context.Background(), stored.ID).Return(&stored, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrPastAppointment,
+		},
+		{
+			name:   "fail, appointment not found",
+			fields: map[string]json.RawMessage{"salon_id": json.RawMessage(`2`)},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByID(context.Background(), stored.ID).Return(nil, appErr.ErrNotFound)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrNotFound,
+		},
+		{
+			name:   "fail, invalid field value",
+			fields: map[string]json.RawMessage{"salon_id": json.RawMessage(`"two"`)},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByID(context.Background(), stored.ID).Return(&stored, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrInvalidBody,
+		},
+		{
+			name:   "fail, patch cancels the booking",
+			fields: map[string]json.RawMessage{"user_id": json.RawMessage(`0`)},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByID(context.Background(), stored.ID).Return(&stored, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
 				return repo, l
 			},
-			want: nil,
-			err:  appErr.ErrDatabase,
+			err: appErr.ErrInvalidBody,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
+				publisher:  publisher,
 			}
-			got, err := s.CreateAppointment(tt.args.ctx, tt.args.app)
+			patch := model.PatchAppointment{ID: stored.ID, Version: 2, Fields: tt.fields}
+			got, err := s.PatchAppointment(context.Background(), patch)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.events, publisher.Types())
 		})
 	}
 }
 
-func TestService_UpdateAppointment(t *testing.T) {
+func TestService_FindAllAppointments(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
 	type args struct {
 		ctx context.Context
-		app model.UpsertAppointment
 	}
 	tests := []struct {
 		name string
-		args args
 		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
-		want *model.AppResponse
+		args args
+		want *model.AppPageResponse
 		err  error
 	}{
 		{
-			name: "success, updated Appointment",
-			args: args{
-				ctx: context.Background(),
-				app: fakeUpsert,
-			},
-			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
-				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(&fakeApp, nil)
-				l := log.NewMockAppointmentLogI(ctrl)
-				return repo, l
-			},
-			want: &fakeAppResponse,
-		},
-		{
-			name: "fail, don't was possible update Appointment",
+			name: "success, returned all Appointments",
 			args: args{
 				ctx: context.Background(),
-				app: fakeUpsert,
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(nil, appErr.ErrDatabase)
+				repo.EXPECT().FindAllAppointments(context.Background(), model.ListOptions{}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
 				return repo, l
 			},
-			err: appErr.ErrDatabase,
+			want: &fakePageResponse,
 		},
 		{
-			name: "fail, don't found Appointmemnt",
+			name: "fail, cannot found any Appointments",
 			args: args{
 				ctx: context.Background(),
-				app: fakeUpsert,
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().UpdateAppointment(context.Background(), fakeApp).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().FindAllAppointments(context.Background(), model.ListOptions{}).Return(nil, appErr.ErrNotFound)
 				l := log.NewMockAppointmentLogI(ctrl)
 				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
 				return repo, l
@@ -201,17 +819,16 @@ func TestService_UpdateAppointment(t *testing.T) {
 			r, l := tt.init()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
 			}
-			got, err := s.UpdateAppointment(tt.args.ctx, tt.args.app)
+			got, err := s.FindAllAppointments(tt.args.ctx, model.ListOptions{})
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func TestService_FindAllAppointments(t *testing.T) {
+func TestService_FindAvailableAppointments(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
 	type args struct {
@@ -219,34 +836,32 @@ func TestService_FindAllAppointments(t *testing.T) {
 	}
 	tests := []struct {
 		name string
-		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
 		args args
-		want []model.AppResponse
+		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want *model.AppPageResponse
 		err  error
 	}{
 		{
-			name: "success, returned all Appointments",
+			name: "success, found all availables Appointments",
 			args: args{
 				ctx: context.Background(),
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAllAppointments(context.Background()).Return([]model.Appointment{
-					fakeApp,
-				}, nil)
+				repo.EXPECT().AvaiableAppointment(context.Background(), model.FindAvailable{}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
 				return repo, l
 			},
-			want: []model.AppResponse{fakeAppResponse},
+			want: &fakePageResponse,
 		},
 		{
-			name: "fail, cannot found any Appointments",
+			name: "fail, not found any available Appointments",
 			args: args{
 				ctx: context.Background(),
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAllAppointments(context.Background()).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().AvaiableAppointment(context.Background(), model.FindAvailable{}).Return(nil, appErr.ErrNotFound)
 				l := log.NewMockAppointmentLogI(ctrl)
 				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
 				return repo, l
@@ -259,52 +874,52 @@ func TestService_FindAllAppointments(t *testing.T) {
 			r, l := tt.init()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
 			}
-			got, err := s.FindAllAppointments(tt.args.ctx)
+			got, err := s.FindAvailableAppointments(tt.args.ctx, model.FindAvailable{})
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func TestService_FindAvailableAppointments(t *testing.T) {
+func TestService_FindAppByID(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
 	type args struct {
 		ctx context.Context
+		app model.FindAppointmentsByIDRequest
 	}
 	tests := []struct {
 		name string
 		args args
 		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
-		want []model.AppResponse
+		want *model.AppResponse
 		err  error
 	}{
 		{
-			name: "success, found all availables Appointments",
+			name: "success, found Appointment by ID",
 			args: args{
 				ctx: context.Background(),
+				app: model.FindAppointmentsByIDRequest{ID: fakeApp.ID},
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().AvaiableAppointment(context.Background()).Return([]model.Appointment{
-					fakeApp,
-				}, nil)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
 				return repo, l
 			},
-			want: []model.AppResponse{fakeAppResponse},
+			want: &fakeAppResponse,
 		},
 		{
-			name: "fail, not found any available Appointments",
+			name: "fail, not found Appointments",
 			args: args{
 				ctx: context.Background(),
+				app: model.FindAppointmentsByIDRequest{ID: fakeApp.ID},
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().AvaiableAppointment(context.Background()).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrNotFound)
 				l := log.NewMockAppointmentLogI(ctrl)
 				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
 				return repo, l
@@ -317,256 +932,225 @@ func TestService_FindAvailableAppointments(t *testing.T) {
 			r, l := tt.init()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
 			}
-			got, err := s.FindAvailableAppointments(tt.args.ctx)
+			got, err := s.FindAppByID(tt.args.ctx, tt.args.app)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func TestService_FindAppByID(t *testing.T) {
+func TestService_FindAppByUserID(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
 	type args struct {
 		ctx context.Context
-		app model.FindAppointmentsByIDRequest
+		id  model.FindAppByUser
 	}
 	tests := []struct {
 		name string
 		args args
-		init func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI)
-		want *model.AppResponse
+		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want *model.AppPageResponse
 		err  error
 	}{
 		{
-			name: "success, found Appointment by ID in memory database",
+			name: "success, found Appointments by UserID",
 			args: args{
 				ctx: context.Background(),
-				app: model.FindAppointmentsByIDRequest{ID: fakeApp.ID},
+				id:  model.FindAppByUser{ID: fakeApp.UserID},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppByIDMemory(fakeApp.ID).Return(&fakeApp, nil)
+				repo.EXPECT().FindAppointmentByUserID(context.Background(), fakeApp.UserID, model.ListOptions{}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				return repo, memory, l
+				return repo, l
 			},
-			want: &fakeAppResponse,
+			want: &fakePageResponse,
 		},
 		{
-			name: "success, found Appointment by ID in database",
+			name: "success, paginated request is passed to the repository",
 			args: args{
 				ctx: context.Background(),
-				app: model.FindAppointmentsByIDRequest{ID: fakeApp.ID},
+				id:  model.FindAppByUser{ID: fakeApp.UserID, ListOptions: model.ListOptions{Limit: 5}},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppByIDMemory(fakeApp.ID).Return(nil, appErr.ErrMemoryDatabase)
-				memory.EXPECT().CreateAppMemoryByID(fakeApp)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				repo.EXPECT().FindAppointmentByUserID(context.Background(), fakeApp.UserID, model.ListOptions{Limit: 5}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase).Return(nil)
-				return repo, memory, l
+				return repo, l
 			},
-			want: &fakeAppResponse,
+			want: &fakePageResponse,
 		},
 		{
-			name: "fail, not found Appointments",
+			name: "fail, don't was possible found Appointments by UserID",
 			args: args{
 				ctx: context.Background(),
-				app: model.FindAppointmentsByIDRequest{ID: fakeApp.ID},
+				id:  model.FindAppByUser{ID: fakeApp.UserID},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppByIDMemory(fakeApp.ID).Return(nil, appErr.ErrMemoryDatabase)
-				memory.EXPECT().CreateAppMemoryByID(fakeApp)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().FindAppointmentByUserID(context.Background(), fakeApp.UserID, model.ListOptions{}).Return(nil, appErr.ErrDatabase)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
-				l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase).Return(nil)
-				return repo, memory, l
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
 			},
-			err: appErr.ErrNotFound,
+			err: appErr.ErrDatabase,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r, m, l := tt.init()
+			r, l := tt.init()
 			s := &Service{
 				repository: r,
-				memory:     m,
 				log:        l,
 			}
-			got, err := s.FindAppByID(tt.args.ctx, tt.args.app)
+			got, err := s.FindAppByUserID(tt.args.ctx, tt.args.id)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func TestService_FindAppByUserID(t *testing.T) {
+func TestService_FindAppBySalonID(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
 	type args struct {
 		ctx context.Context
-		id  model.FindAppByUser
+		id  model.FindAppBySalon
 	}
 	tests := []struct {
 		name string
 		args args
-		init func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI)
-		want []model.AppResponse
+		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want *model.AppPageResponse
 		err  error
 	}{
 		{
-			name: "success, found Appointment by UserID in memory database",
+			name: "success, found Appointments by SalonID",
 			args: args{
 				ctx: context.Background(),
-				id:  model.FindAppByUser{ID: fakeApp.UserID},
+				id:  model.FindAppBySalon{ID: fakeApp.SalonID},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentByUserID(context.Background(), fakeApp.UserID).Return([]model.Appointment{fakeApp}, nil)
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppByUserIDMemory(fakeApp.UserID).Return([]model.Appointment{fakeApp}, nil)
+				repo.EXPECT().FindAppointmentBySalonID(context.Background(), fakeApp.SalonID, model.ListOptions{}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				return repo, memory, l
+				return repo, l
 			},
-			want: []model.AppResponse{fakeAppResponse},
+			want: &fakePageResponse,
 		},
 		{
-			name: "success, found Appointment by UserID in database",
+			name: "success, filtered request is passed to the repository",
 			args: args{
 				ctx: context.Background(),
-				id:  model.FindAppByUser{ID: fakeApp.UserID},
+				id:  model.FindAppBySalon{ID: fakeApp.SalonID, ListOptions: model.ListOptions{Sort: model.SortDateDesc}},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppByUserIDMemory(fakeApp.UserID).Return(nil, appErr.ErrMemoryDatabase)
-				memory.EXPECT().CreateAppMemoryByUserID([]model.Appointment{fakeApp}).Return(nil)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentByUserID(context.Background(), fakeApp.UserID).Return([]model.Appointment{fakeApp}, nil)
+				repo.EXPECT().FindAppointmentBySalonID(context.Background(), fakeApp.SalonID, model.ListOptions{Sort: model.SortDateDesc}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase).Return(nil)
-				return repo, memory, l
+				return repo, l
 			},
-			want: []model.AppResponse{fakeAppResponse},
+			want: &fakePageResponse,
 		},
 		{
-			name: "fail, don't was possible found Appointment by UserID",
+			name: "fail, don't was possible found Appointments by SalonID",
 			args: args{
 				ctx: context.Background(),
-				id:  model.FindAppByUser{ID: fakeApp.UserID},
+				id:  model.FindAppBySalon{ID: fakeApp.SalonID},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppByUserIDMemory(fakeApp.UserID).Return(nil, appErr.ErrMemoryDatabase)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentByUserID(context.Background(), fakeApp.UserID).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().FindAppointmentBySalonID(context.Background(), fakeApp.SalonID, model.ListOptions{}).Return(nil, appErr.ErrDatabase)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase).Return(nil)
-				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
-				return repo, memory, l
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
 			},
-			err: appErr.ErrNotFound,
+			err: appErr.ErrDatabase,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r, m, l := tt.init()
+			r, l := tt.init()
 			s := &Service{
 				repository: r,
-				memory:     m,
 				log:        l,
 			}
-			got, err := s.FindAppByUserID(tt.args.ctx, tt.args.id)
+			got, err := s.FindAppBySalonID(tt.args.ctx, tt.args.id)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func TestService_FindAppBySalonID(t *testing.T) {
+func TestService_FindAppByProfessionalID(t *testing.T) {
 	var ctrl = gomock.NewController(t)
 	ctrl.Finish()
 	type args struct {
 		ctx context.Context
-		id  model.FindAppBySalon
+		id  model.FindAppByProfessional
 	}
 	tests := []struct {
 		name string
 		args args
-		init func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI)
-		want []model.AppResponse
+		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want *model.AppPageResponse
 		err  error
 	}{
 		{
-			name: "success, found Appointments by SalonID in database memory",
+			name: "success, found Appointments by ProfessionalID",
 			args: args{
 				ctx: context.Background(),
-				id:  model.FindAppBySalon{ID: fakeApp.SalonID},
+				id:  model.FindAppByProfessional{ID: fakeProfessionalID},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppBySalonIDMemory(fakeApp.SalonID).Return([]model.Appointment{fakeApp}, nil)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				repo.EXPECT().FindAppointmentByProfessionalID(context.Background(), fakeProfessionalID, model.ListOptions{}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				return repo, memory, l
+				return repo, l
 			},
-			want: []model.AppResponse{fakeAppResponse},
+			want: &fakePageResponse,
 		},
 		{
-			name: "success, found Appointments by SalonID in database",
+			name: "success, filtered request is passed to the repository",
 			args: args{
 				ctx: context.Background(),
-				id:  model.FindAppBySalon{ID: fakeApp.SalonID},
+				id:  model.FindAppByProfessional{ID: fakeProfessionalID, ListOptions: model.ListOptions{Sort: model.SortDateDesc}},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppBySalonIDMemory(fakeApp.SalonID).Return(nil, appErr.ErrMemoryDatabase)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentBySalonID(context.Background(), fakeApp.SalonID).Return([]model.Appointment{fakeApp}, nil)
-				memory.EXPECT().CreateAppMemoryBySalonID([]model.Appointment{fakeApp}).Return(nil)
+				repo.EXPECT().FindAppointmentByProfessionalID(context.Background(), fakeProfessionalID, model.ListOptions{Sort: model.SortDateDesc}).Return(&fakePage, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase).Return(nil)
-				return repo, memory, l
+				return repo, l
 			},
-			want: []model.AppResponse{fakeAppResponse},
+			want: &fakePageResponse,
 		},
 		{
-			name: "fail, don't was possible found Appointments",
+			name: "fail, don't was possible found Appointments by ProfessionalID",
 			args: args{
 				ctx: context.Background(),
-				id:  model.FindAppBySalon{ID: fakeApp.SalonID},
+				id:  model.FindAppByProfessional{ID: fakeProfessionalID},
 			},
-			init: func() (*repository.MockAppointmentRepositoryI, *repository.MockAppointmentMemoryI, *log.MockAppointmentLogI) {
-				memory := repository.NewMockAppointmentMemoryI(ctrl)
-				memory.EXPECT().FindAppBySalonIDMemory(fakeApp.SalonID).Return(nil, appErr.ErrMemoryDatabase)
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
-				repo.EXPECT().FindAppointmentBySalonID(context.Background(), fakeApp.SalonID).Return(nil, appErr.ErrNotFound)
+				repo.EXPECT().FindAppointmentByProfessionalID(context.Background(), fakeProfessionalID, model.ListOptions{}).Return(nil, appErr.ErrDatabase)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrMemoryDatabase).Return(nil)
-				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
-				return repo, memory, l
+				l.EXPECT().LogWithTime(appErr.ErrDatabase).Return(nil)
+				return repo, l
 			},
-			err: appErr.ErrNotFound,
+			err: appErr.ErrDatabase,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r, m, l := tt.init()
+			r, l := tt.init()
 			s := &Service{
 				repository: r,
-				memory:     m,
 				log:        l,
 			}
-			got, err := s.FindAppBySalonID(tt.args.ctx, tt.args.id)
+			got, err := s.FindAppByProfessionalID(tt.args.ctx, tt.args.id)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
@@ -575,26 +1159,29 @@ func TestService_FindAppBySalonID(t *testing.T) {
 
 func TestService_MakeAppointment(t *testing.T) {
 	var ctrl = gomock.NewController(t)
-	ctrl.Finish()
+	defer ctrl.Finish()
 	type args struct {
 		ctx  context.Context
 		make model.MakeAppointment
 	}
 	tests := []struct {
-		name string
-		args args
-		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
-		want *model.AppResponse
-		err  error
+		name   string
+		args   args
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want   *model.AppResponse
+		err    error
+		events []event.Type
 	}{
 		{
-			name: "success, Appointment marked",
+			name:   "success, Appointment marked",
+			events: []event.Type{event.TypeBooked},
 			args: args{
 				ctx:  context.Background(),
 				make: model.MakeAppointment{ID: fakeApp.ID, UserID: fakeApp.UserID},
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
 				repo.EXPECT().MakeAppointment(context.Background(), fakeApp.ID, fakeApp.UserID).Return(&fakeApp, nil)
 				l := log.NewMockAppointmentLogI(ctrl)
 				return repo, l
@@ -609,6 +1196,7 @@ func TestService_MakeAppointment(t *testing.T) {
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
 				repo.EXPECT().MakeAppointment(context.Background(), fakeApp.ID, fakeApp.UserID).Return(nil, appErr.ErrNotFound)
 				l := log.NewMockAppointmentLogI(ctrl)
 				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
@@ -620,42 +1208,70 @@ func TestService_MakeAppointment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
+				publisher:  publisher,
 			}
 			got, err := s.MakeAppointment(tt.args.ctx, tt.args.make)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.events, publisher.Types())
 		})
 	}
 }
 
 func TestService_DeleteApp(t *testing.T) {
 	var ctrl = gomock.NewController(t)
-	ctrl.Finish()
+	defer ctrl.Finish()
 	type args struct {
 		ctx context.Context
 		app model.DeleteAppointment
 	}
 	tests := []struct {
-		name string
-		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
-		args args
-		err  error
+		name   string
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		args   args
+		err    error
+		events []event.Type
 	}{
 		{
-			name: "success, deleted appointment",
+			name:   "success, deleted appointment",
+			events: []event.Type{event.TypeDeleted},
 			args: args{
 				ctx: context.Background(),
 				app: model.DeleteAppointment{
-					ID: fakeApp.ID,
+					ID:      fakeApp.ID,
+					Version: 2,
+				},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				r := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				r.EXPECT().DeleteAppointment(context.Background(), fakeApp.ID, int64(2)).Return(nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return r, l
+			},
+		},
+		{
+			name:   "success, deleted appointment at whatever version is stored",
+			events: []event.Type{event.TypeDeleted},
+			args: args{
+				ctx: context.Background(),
+				app: model.DeleteAppointment{
+					ID:      fakeApp.ID,
+					Version: model.AnyVersion,
 				},
 			},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				storedApp := fakeApp
+				storedApp.Version = 4
 				r := repository.NewMockAppointmentRepositoryI(ctrl)
-				r.EXPECT().DeleteAppointment(context.Background(), fakeApp.ID).Return(nil)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&storedApp, nil)
+				r.EXPECT().DeleteAppointment(context.Background(), fakeApp.ID, int64(4)).Return(nil)
 				l := log.NewMockAppointmentLogI(ctrl)
 				return r, l
 			},
@@ -664,9 +1280,11 @@ func TestService_DeleteApp(t *testing.T) {
 			name: "fail, do not found app for delete",
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				r := repository.NewMockAppointmentRepositoryI(ctrl)
-				r.EXPECT().DeleteAppointment(context.Background(), fakeApp.ID).Return(appErr.ErrNotFound)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrNotFound)
+				r.EXPECT().DeleteAppointment(context.Background(), fakeApp.ID, int64(0)).Return(appErr.ErrNotFound)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
+				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil).Times(2)
 				return r, l
 			},
 			args: args{
@@ -677,47 +1295,96 @@ func TestService_DeleteApp(t *testing.T) {
 			},
 			err: appErr.ErrNotFound,
 		},
+		{
+			name: "fail, appointment changed meanwhile",
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				r := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				r.EXPECT().DeleteAppointment(context.Background(), fakeApp.ID, int64(1)).Return(appErr.ErrVersionMismatch)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrVersionMismatch).Return(nil)
+				return r, l
+			},
+			args: args{
+				ctx: context.Background(),
+				app: model.DeleteAppointment{
+					ID:      fakeApp.ID,
+					Version: 1,
+				},
+			},
+			err: appErr.ErrVersionMismatch,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
+				publisher:  publisher,
 			}
 			err := s.DeleteApp(tt.args.ctx, tt.args.app)
 			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.events, publisher.Types())
 		})
 	}
 }
 
 func TestService_CancelAppointment(t *testing.T) {
 	var ctrl = gomock.NewController(t)
-	ctrl.Finish()
+	defer ctrl.Finish()
 	type args struct {
 		ctx context.Context
-		app model.MakeAppointment
+		app model.CancelAppointment
 	}
 	tests := []struct {
-		name string
-		init func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
-		args args
-		err  error
+		name   string
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		args   args
+		err    error
+		events []event.Type
 	}{
 		{
-			name: "success, canceled appointment",
+			name:   "success, canceled appointment",
+			events: []event.Type{event.TypeCancelled},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				r := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				r.EXPECT().CancelAppointment(context.Background(), fakeApp.ID, fakeApp.UserID, int64(2)).Return(nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return r, l
+			},
+			args: args{
+				ctx: context.Background(),
+				app: model.CancelAppointment{
+					ID:      fakeApp.ID,
+					UserID:  fakeApp.UserID,
+					Version: 2,
+				},
+			},
+		},
+		{
+			name:   "success, canceled appointment at whatever version is stored",
+			events: []event.Type{event.TypeCancelled},
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				storedApp := fakeApp
+				storedApp.Version = 4
 				r := repository.NewMockAppointmentRepositoryI(ctrl)
-				r.EXPECT().CancelAppointment(context.Background(), fakeApp.ID, fakeApp.UserID).Return(nil)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&storedApp, nil)
+				r.EXPECT().CancelAppointment(context.Background(), fakeApp.ID, fakeApp.UserID, int64(4)).Return(nil)
 				l := log.NewMockAppointmentLogI(ctrl)
 				return r, l
 			},
 			args: args{
 				ctx: context.Background(),
-				app: model.MakeAppointment{
-					ID:     fakeApp.ID,
-					UserID: fakeApp.UserID,
+				app: model.CancelAppointment{
+					ID:      fakeApp.ID,
+					UserID:  fakeApp.UserID,
+					Version: model.AnyVersion,
 				},
 			},
 		},
@@ -725,31 +1392,166 @@ func TestService_CancelAppointment(t *testing.T) {
 			name: "fail, don't possible cancel appointment",
 			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
 				r := repository.NewMockAppointmentRepositoryI(ctrl)
-				r.EXPECT().CancelAppointment(context.Background(), fakeApp.ID, fakeApp.UserID).Return(appErr.ErrNotFound)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrNotFound)
+				r.EXPECT().CancelAppointment(context.Background(), fakeApp.ID, fakeApp.UserID, int64(2)).Return(appErr.ErrNotFound)
 				l := log.NewMockAppointmentLogI(ctrl)
-				l.EXPECT().LogWithTime(appErr.ErrNotFound)
+				l.EXPECT().LogWithTime(appErr.ErrNotFound).Times(2)
 				return r, l
 			},
 			args: args{
 				ctx: context.Background(),
-				app: model.MakeAppointment{
-					ID:     fakeApp.ID,
-					UserID: fakeApp.UserID,
+				app: model.CancelAppointment{
+					ID:      fakeApp.ID,
+					UserID:  fakeApp.UserID,
+					Version: 2,
 				},
 			},
 			err: appErr.ErrNotFound,
 		},
+		{
+			name: "fail, cannot cancel a completed appointment",
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				completedApp := fakeApp
+				completedApp.Status = model.StatusCompleted
+				r := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(r)
+				r.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&completedApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any())
+				return r, l
+			},
+			args: args{
+				ctx: context.Background(),
+				app: model.CancelAppointment{
+					ID:      fakeApp.ID,
+					UserID:  fakeApp.UserID,
+					Version: 2,
+				},
+			},
+			err: appErr.ErrInvalidTransition,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
 			s := &Service{
 				repository: r,
-				memory:     repository.NewMockAppointmentMemoryI(ctrl),
 				log:        l,
+				publisher:  publisher,
 			}
 			err := s.CancelAppointment(tt.args.ctx, tt.args.app)
 			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.events, publisher.Types())
+		})
+	}
+}
+
+func TestService_ChangeStatus(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	type args struct {
+		ctx    context.Context
+		change model.ChangeStatus
+	}
+	confirmedApp := fakeApp
+	confirmedApp.Status = model.StatusConfirmed
+	confirmedResponse := fakeAppResponse
+	confirmedResponse.Status = model.StatusConfirmed
+	availableApp := fakeApp
+	availableApp.UserID = 0
+	availableApp.Status = model.StatusAvailable
+	tests := []struct {
+		name   string
+		args   args
+		init   func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI)
+		want   *model.AppResponse
+		err    error
+		events []event.Type
+	}{
+		{
+			name:   "success, confirmed booked Appointment",
+			events: []event.Type{event.TypeConfirmed},
+			args: args{
+				ctx:    context.Background(),
+				change: model.ChangeStatus{ID: fakeApp.ID, Status: model.StatusConfirmed},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				repo.EXPECT().UpdateStatus(context.Background(), fakeApp.ID, model.StatusBooked, model.StatusConfirmed).
+					Return(&confirmedApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				return repo, l
+			},
+			want: &confirmedResponse,
+		},
+		{
+			name: "fail, cannot complete an available Appointment",
+			args: args{
+				ctx:    context.Background(),
+				change: model.ChangeStatus{ID: fakeApp.ID, Status: model.StatusCompleted},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&availableApp, nil)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(gomock.Any()).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrInvalidTransition,
+		},
+		{
+			name: "fail, Appointment changed concurrently",
+			args: args{
+				ctx:    context.Background(),
+				change: model.ChangeStatus{ID: fakeApp.ID, Status: model.StatusNoShow},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(&fakeApp, nil)
+				repo.EXPECT().UpdateStatus(context.Background(), fakeApp.ID, model.StatusBooked, model.StatusNoShow).
+					Return(nil, appErr.ErrInvalidTransition)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrInvalidTransition).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrInvalidTransition,
+		},
+		{
+			name: "fail, not found Appointment",
+			args: args{
+				ctx:    context.Background(),
+				change: model.ChangeStatus{ID: fakeApp.ID, Status: model.StatusConfirmed},
+			},
+			init: func() (*repository.MockAppointmentRepositoryI, *log.MockAppointmentLogI) {
+				repo := repository.NewMockAppointmentRepositoryI(ctrl)
+				inTransaction(repo)
+				repo.EXPECT().FindAppointmentByID(context.Background(), fakeApp.ID).Return(nil, appErr.ErrNotFound)
+				l := log.NewMockAppointmentLogI(ctrl)
+				l.EXPECT().LogWithTime(appErr.ErrNotFound).Return(nil)
+				return repo, l
+			},
+			err: appErr.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, l := tt.init()
+			publisher := event.NewMemoryPublisher()
+			s := &Service{
+				repository: r,
+				log:        l,
+				publisher:  publisher,
+			}
+			got, err := s.ChangeStatus(tt.args.ctx, tt.args.change)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.events, publisher.Types())
 		})
 	}
 }
@@ -9,92 +9,343 @@ import (
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments"
 	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/service"
+	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/transport/amqp"
 	"github.com/pkg/errors"
 	delivery "github.com/streadway/amqp"
 )
 
-const queue = 2
+const (
+	CreateQueue             = "create-appointment"
+	MakeQueue               = "make-appointment"
+	GenerateQueue           = "generate-appointments"
+	UpdateQueue             = "update-appointment"
+	CancelQueue             = "cancel-appointment"
+	DeleteQueue             = "delete-appointment"
+	FindQueue               = "find-appointment"
+	FindAllQueue            = "find-appointments"
+	FindByUserQueue         = "find-appointments-by-user"
+	FindBySalonQueue        = "find-appointments-by-salon"
+	FindByProfessionalQueue = "find-appointments-by-professional"
+	AvailableQueue          = "find-available-appointments"
+)
 
-func NewBroker(svc service.AppointmentServiceI, ch amqp.Channel) error {
-	wg := new(sync.WaitGroup)
-	wg.Add(queue)
-	options := []amqp.SubscriberOption{
-		amqp.SubscriberErrorEncoder(errorSubscriber),
+// headerIdempotencyKey is the delivery header carrying the idempotency key,
+// deliveries without it are deduplicated by their MessageId.
+const headerIdempotencyKey = "idempotency-key"
+
+// Channel is the broker channel consumed by NewBroker.
+// It is implemented by *amqp.Channel.
+type Channel interface {
+	amqp.Channel
+	Cancel(consumer string, noWait bool) error
+}
+
+// subscription binds a queue to the endpoint serving its messages.
+type subscription struct {
+	queue    string
+	endpoint endpoint.Endpoint
+	dec      amqp.DecodeRequestFunc
+}
+
+// subscriptions lists the queues served by the broker. Writes are made
+// idempotent under the same operations as the HTTP routes, so a key is
+// honored whichever transport the request came through.
+func subscriptions(svc service.AppointmentServiceI, store repository.IdempotencyI, t Topology) []subscription {
+	return []subscription{
+		{t.CreateQueue, appointments.Idempotent(store, CreateQueue)(appointments.CreateAppointment(svc)), decodeCreateApp},
+		{t.MakeQueue, appointments.Idempotent(store, MakeQueue)(appointments.MakeAppointmentByUser(svc)), decodeMakeAppointment},
+		{t.GenerateQueue, appointments.Idempotent(store, GenerateQueue)(appointments.GenerateAppointments(svc)), decodeGenerateAppointments},
+		{t.UpdateQueue, appointments.Idempotent(store, UpdateQueue)(appointments.UpdateAppointmentByUser(svc)), decodeUpdateAppointment},
+		{t.CancelQueue, appointments.Idempotent(store, CancelQueue)(appointments.CancelAppointment(svc)), decodeCancelAppointment},
+		{t.DeleteQueue, appointments.Idempotent(store, DeleteQueue)(appointments.DeleteAppointment(svc)), decodeDeleteAppointment},
+		{t.FindQueue, appointments.FindAppointmentByID(svc), decodeFindAppointment},
+		{t.FindAllQueue, appointments.FindAllAppointment(svc), decodeFindAllAppointments},
+		{t.FindByUserQueue, appointments.FindAppointmentByUser(svc), decodeFindAppointmentsByUser},
+		{t.FindBySalonQueue, appointments.FindAppointmentBySalon(svc), decodeFindAppointmentsBySalon},
+		{t.FindByProfessionalQueue, appointments.FindAppointmentByProfessional(svc), decodeFindAppointmentsByProfessional},
+		{t.AvailableQueue, appointments.AvailableAppointment(svc), decodeAvailableAppointments},
 	}
+}
 
-	createApp := amqp.NewSubscriber(
-		appointments.CreateAppointment(svc),
-		decodeCreateApp,
-		encodeResponseFunc,
-		options...,
-	).ServeDelivery(ch)
+// NewBroker consumes the queues of the topology, which must already be
+// declared, see DeclareTopology. It returns when the deliveries are closed
+// by the broker, or once ctx is done, after cancelling the consumers and
+// waiting for the in-flight deliveries to be handled. Redelivered writes are
+// answered from store instead of being applied again, store may be nil.
+func NewBroker(ctx context.Context, svc service.AppointmentServiceI, store repository.IdempotencyI, ch Channel, t Topology) error {
+	wg := new(sync.WaitGroup)
+	for _, sub := range subscriptions(svc, store, t) {
+		serve := amqp.NewSubscriber(
+			sub.endpoint,
+			sub.dec,
+			encodeResponseFunc,
+			subscriberOptions(sub.queue, t.Retry)...,
+		).ServeDelivery(ch)
 
-	createMessage, err := ch.Consume("create-appointment", "", false, false, false, false, nil)
-	if err != nil {
-		return err
+		deliveries, err := ch.Consume(sub.queue, t.consumerTag(sub.queue), false, false, false, false, nil)
+		if err != nil {
+			return errors.Wrapf(err, "consume %s", sub.queue)
+		}
+
+		wg.Add(1)
+		go consume(serve, deliveries, wg)
 	}
 
-	makeApp := amqp.NewSubscriber(
-		appointments.MakeAppointmentByUser(svc),
-		decodeMakeAppointment,
-		encodeResponseFunc,
-		options...,
-	).ServeDelivery(ch)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 
-	makeMessage, err := ch.Consume("make-appointment", "", false, false, false, false, nil)
-	if err != nil {
-		return err
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
 	}
 
-	go createchannel(createApp, createMessage, wg)
-	go makechannel(makeApp, makeMessage, wg)
-	wg.Wait()
-	return nil
+	// Cancelling a consumer closes its deliveries, deliveries not handled yet
+	// are requeued by the broker once the channel is closed.
+	var cancelErr error
+	for _, queue := range t.Queues() {
+		if err := ch.Cancel(t.consumerTag(queue), false); err != nil && cancelErr == nil {
+			cancelErr = errors.Wrapf(err, "cancel consumer of %s", queue)
+		}
+	}
+
+	<-done
+	return cancelErr
 }
 
-func createchannel(del func(del *delivery.Delivery), message <-chan delivery.Delivery, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for d := range message {
-		del(&d)
-		log.Printf("Received a message: %s", d.Body)
+// subscriberOptions make every subscriber of queue reply to the caller with
+// the response or the error, following RPC over AMQP semantics, and retry or
+// dead-letter failed deliveries according to policy.
+func subscriberOptions(queue string, policy RetryPolicy) []amqp.SubscriberOption {
+	return []amqp.SubscriberOption{
+		amqp.SubscriberResponsePublisher(replyPublisher),
+		amqp.SubscriberErrorEncoder(errorSubscriber(queue, policy)),
+		amqp.SubscriberBefore(deliveryIdempotencyKey),
+	}
+}
+
+func deliveryIdempotencyKey(ctx context.Context, _ *delivery.Publishing, d *delivery.Delivery) context.Context {
+	if key, ok := d.Headers[headerIdempotencyKey].(string); ok && key != "" {
+		return appointments.WithIdempotencyKey(ctx, key)
 	}
-	log.Printf(" [*] Waiting for messages. To exit press CTRL+C")
+
+	return appointments.WithIdempotencyKey(ctx, d.MessageId)
 }
 
-func makechannel(del func(del *delivery.Delivery), message <-chan delivery.Delivery, wg *sync.WaitGroup) {
+// consume serves every delivery until the deliveries are closed.
+func consume(serve func(*delivery.Delivery), deliveries <-chan delivery.Delivery, wg *sync.WaitGroup) {
 	defer wg.Done()
-	for d := range message {
-		del(&d)
+	for d := range deliveries {
+		serve(&d)
 		log.Printf("Received a message: %s", d.Body)
 	}
 }
 
 func decodeCreateApp(_ context.Context, r *delivery.Delivery) (interface{}, error) {
 	var app model.UpsertAppointment
-	if err := json.Unmarshal(r.Body, &app); err != nil {
-		return nil, appErr.ErrInvalidBody
-	}
-	if err := validate.Struct(app); err != nil {
-		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
 	}
+
 	return app, nil
 }
 
 func decodeMakeAppointment(_ context.Context, r *delivery.Delivery) (interface{}, error) {
 	var app model.MakeAppointment
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeGenerateAppointments(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var template model.SlotTemplate
+	if err := decodeBody(r, &template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func decodeUpdateAppointment(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.UpsertAppointment
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if app.ID == "" {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "id is required")
+	}
+	if err := checkVersion(r); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeCancelAppointment(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.CancelAppointment
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(r); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeDeleteAppointment(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.DeleteAppointment
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if app.ID == "" {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "id is required")
+	}
+	if err := checkVersion(r); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeFindAppointment(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.FindAppointmentsByIDRequest
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if app.ID == "" {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "id is required")
+	}
+
+	return app, nil
+}
+
+func decodeFindAllAppointments(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var opts model.ListOptions
+	if err := decodeBody(r, &opts); err != nil {
+		return nil, err
+	}
+	if err := checkListOptions(opts); err != nil {
+		return nil, err
+	}
 
-	if err := json.Unmarshal(r.Body, &app); err != nil {
-		return nil, appErr.ErrInvalidBody
+	return opts, nil
+}
+
+func decodeFindAppointmentsByUser(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.FindAppByUser
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if app.ID <= 0 {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "id must be positive")
+	}
+	if err := checkListOptions(app.ListOptions); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeFindAppointmentsBySalon(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.FindAppBySalon
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if app.ID <= 0 {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "id must be positive")
+	}
+	if err := checkListOptions(app.ListOptions); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeFindAppointmentsByProfessional(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.FindAppByProfessional
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
 	}
-	if err := validate.Struct(app); err != nil {
-		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	if app.ID <= 0 {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "id must be positive")
+	}
+	if err := checkListOptions(app.ListOptions); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+func decodeAvailableAppointments(_ context.Context, r *delivery.Delivery) (interface{}, error) {
+	var app model.FindAvailable
+	if err := decodeBody(r, &app); err != nil {
+		return nil, err
+	}
+	if err := checkListOptions(app.ListOptions); err != nil {
+		return nil, err
 	}
 
 	return app, nil
 }
 
+// decodeBody unmarshals the JSON body of the delivery into req and validates
+// it, both failures are reported as ErrInvalidBody.
+// checkVersion fails a write message that does not say which version it
+// applies to, as the If-Match header is required over HTTP. Versions are
+// never negative, so no message can stand for any version.
+func checkVersion(r *delivery.Delivery) error {
+	var msg struct {
+		Version *int64 `json:"version"`
+	}
+	if err := json.Unmarshal(r.Body, &msg); err != nil {
+		return appErr.ErrInvalidBody
+	}
+	if msg.Version == nil {
+		return errors.Wrap(appErr.ErrPreconditionRequired, "version is required")
+	}
+	if *msg.Version < 0 {
+		return errors.Wrap(appErr.ErrInvalidBody, "version cannot be negative")
+	}
+
+	return nil
+}
+
+func decodeBody(r *delivery.Delivery, req interface{}) error {
+	if err := json.Unmarshal(r.Body, req); err != nil {
+		return appErr.ErrInvalidBody
+	}
+	if err := validate.Struct(req); err != nil {
+		return errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	return nil
+}
+
+// checkListOptions applies the checks decodeListOptions does on the query
+// string to list options sent in a message body.
+func checkListOptions(opts model.ListOptions) error {
+	if opts.Page != "" {
+		if _, err := model.DecodePageToken(opts.Page); err != nil {
+			return errors.Wrap(appErr.ErrInvalidBody, err.Error())
+		}
+	}
+
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.To.After(opts.From) {
+		return errors.Wrap(appErr.ErrInvalidBody, "to must be after from")
+	}
+
+	return nil
+}
+
 func encodeResponseFunc(ctx context.Context, p *delivery.Publishing, input interface{}) error {
 	var err error
 	p.Body, err = json.Marshal(input)
@@ -104,14 +355,91 @@ func encodeResponseFunc(ctx context.Context, p *delivery.Publishing, input inter
 	return nil
 }
 
-func errorSubscriber(_ context.Context, err error, deliv *delivery.Delivery, ch amqp.Channel, p *delivery.Publishing) {
-	resp, _ := appErr.RESTErrorBussines.ErrorProcess(err)
-	p.Body, err = json.Marshal(map[string]string{"error": resp})
-	if err != nil {
-		log.Printf("Encoding error, nothing much we can do: %v", err)
+// replyPublisher sends the response back to the caller, when it asked for
+// one, and acknowledges the delivery.
+func replyPublisher(_ context.Context, deliv *delivery.Delivery, ch amqp.Channel, p *delivery.Publishing) error {
+	p.Headers = delivery.Table{"code": int32(200)}
+	if err := reply(deliv, ch, p); err != nil {
+		return err
 	}
 
-	if err := deliv.Ack(true); err != nil {
-		log.Printf("Cannot be return a response %v", err)
+	return deliv.Ack(false)
+}
+
+// errorSubscriber settles a failed delivery of queue. Transient errors are
+// sent to the next delay queue of the policy and retried later without
+// replying. Poison messages, and transient errors past the last retry, are
+// published to the dead-letter exchange with the reason in the headers.
+// Every other error is a business outcome and only replied to, with its
+// code. Only the failed delivery is acked; when the message cannot be moved
+// it is requeued instead, so it is never lost.
+func errorSubscriber(queue string, policy RetryPolicy) amqp.ErrorEncoder {
+	return func(_ context.Context, err error, deliv *delivery.Delivery, ch amqp.Channel, p *delivery.Publishing) {
+		resp, code := appErr.RESTErrorBussines.ErrorProcess(err)
+		attempt := retryCount(deliv)
+		if transient(err) && attempt < len(policy.Delays) {
+			retry := republish(deliv, delivery.Table{headerRetryCount: int32(attempt + 1)})
+			if err := ch.Publish("", retryQueue(queue, attempt+1), false, false, retry); err != nil {
+				log.Printf("Cannot retry the message %v", err)
+				nack(deliv)
+				return
+			}
+
+			ack(deliv)
+			return
+		}
+
+		if transient(err) || poison(err) {
+			dead := republish(deliv, delivery.Table{
+				headerRetryCount:  int32(attempt),
+				headerErrorReason: err.Error(),
+				headerErrorCode:   int32(code),
+				headerQueue:       queue,
+			})
+			if err := ch.Publish(policy.DeadLetterExchange, queue, false, false, dead); err != nil {
+				log.Printf("Cannot dead-letter the message %v", err)
+				nack(deliv)
+				return
+			}
+		}
+
+		p.Headers = delivery.Table{"code": int32(code)}
+		p.Body, err = json.Marshal(map[string]string{"error": resp})
+		if err != nil {
+			log.Printf("Encoding error, nothing much we can do: %v", err)
+		}
+
+		if err := reply(deliv, ch, p); err != nil {
+			log.Printf("Cannot be return a response %v", err)
+		}
+
+		ack(deliv)
+	}
+}
+
+// ack acknowledges only deliv, never the deliveries received before it.
+func ack(deliv *delivery.Delivery) {
+	if err := deliv.Ack(false); err != nil {
+		log.Printf("Cannot ack the message %v", err)
 	}
 }
+
+// nack returns deliv to its queue to be delivered again.
+func nack(deliv *delivery.Delivery) {
+	if err := deliv.Nack(false, true); err != nil {
+		log.Printf("Cannot nack the message %v", err)
+	}
+}
+
+// reply publishes p to the ReplyTo queue of the delivery through the default
+// exchange, correlated with the request. Deliveries without ReplyTo are fire
+// and forget, so nothing is published for them.
+func reply(deliv *delivery.Delivery, ch amqp.Channel, p *delivery.Publishing) error {
+	if deliv.ReplyTo == "" {
+		return nil
+	}
+
+	p.ContentType = "application/json"
+	p.CorrelationId = deliv.CorrelationId
+	return ch.Publish("", deliv.ReplyTo, false, false, *p)
+}
@@ -2,16 +2,89 @@ package transport
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments"
 	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/service"
 	"github.com/go-kit/kit/transport/amqp"
+	gomock "github.com/golang/mock/gomock"
 	delivery "github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// fakeChannel records what is published instead of sending it to a broker.
+type fakeChannel struct {
+	amqp.Channel
+	keys      []string
+	published []delivery.Publishing
+	err       error
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg delivery.Publishing) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.keys = append(c.keys, key)
+	c.published = append(c.published, msg)
+	return nil
+}
+
+// fakeConsumer hands out a deliveries channel per consumer tag, closed when
+// the consumer is cancelled.
+type fakeConsumer struct {
+	fakeChannel
+	mu         sync.Mutex
+	deliveries map[string]chan delivery.Delivery
+	cancelled  []string
+}
+
+func (c *fakeConsumer) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args delivery.Table) (<-chan delivery.Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deliveries == nil {
+		c.deliveries = make(map[string]chan delivery.Delivery)
+	}
+	c.deliveries[consumer] = make(chan delivery.Delivery, 1)
+	return c.deliveries[consumer], nil
+}
+
+func (c *fakeConsumer) Cancel(consumer string, noWait bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelled = append(c.cancelled, consumer)
+	close(c.deliveries[consumer])
+	return nil
+}
+
+// fakeAcknowledger records how a delivery was acknowledged.
+type fakeAcknowledger struct {
+	acked, nacked, rejected int
+	requeued                bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked++
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.nacked++
+	a.requeued = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.rejected++
+	a.requeued = requeue
+	return nil
+}
+
 func Test_decodeCreateApp(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -99,48 +172,184 @@ func Test_encodeResponseFunc(t *testing.T) {
 }
 
 func Test_errorSubscriber(t *testing.T) {
+	policy := RetryPolicy{Delays: []time.Duration{time.Second, time.Minute}, DeadLetterExchange: "dlx"}
+	tests := []struct {
+		name     string
+		err      error
+		headers  delivery.Table
+		ch       *fakeChannel
+		keys     []string
+		retry    int32
+		reply    string
+		code     int32
+		acked    int
+		requeued bool
+	}{
+		{
+			name:  "success, invalid body dead-lettered and replied",
+			err:   appErr.ErrInvalidBody,
+			ch:    &fakeChannel{},
+			keys:  []string{CreateQueue, "amq.gen-reply"},
+			reply: `{"error":"Invalid body"}`,
+			code:  400,
+			acked: 1,
+		},
+		{
+			name:  "success, unclassified error dead-lettered and replied",
+			err:   errors.New("unexpected end of JSON input"),
+			ch:    &fakeChannel{},
+			keys:  []string{CreateQueue, "amq.gen-reply"},
+			reply: `{"error":"Sorry, something went wrong"}`,
+			code:  500,
+			acked: 1,
+		},
+		{
+			name:  "success, already booked only replied",
+			err:   appErr.ErrAlreadyBooked,
+			ch:    &fakeChannel{},
+			keys:  []string{"amq.gen-reply"},
+			reply: `{"error":"Appointment already booked"}`,
+			code:  409,
+			acked: 1,
+		},
+		{
+			name:  "success, not found only replied",
+			err:   appErr.ErrNotFound,
+			ch:    &fakeChannel{},
+			keys:  []string{"amq.gen-reply"},
+			reply: `{"error":"Appointment not found"}`,
+			code:  404,
+			acked: 1,
+		},
+		{
+			name:  "success, database error retried",
+			err:   appErr.ErrDatabase,
+			ch:    &fakeChannel{},
+			keys:  []string{"create-appointment.retry.1"},
+			retry: 1,
+			acked: 1,
+		},
+		{
+			name:    "success, memory database error retried again",
+			err:     appErr.ErrMemoryDatabase,
+			headers: delivery.Table{headerRetryCount: int32(1)},
+			ch:      &fakeChannel{},
+			keys:    []string{"create-appointment.retry.2"},
+			retry:   2,
+			acked:   1,
+		},
+		{
+			name:    "success, database error dead-lettered after the last retry",
+			err:     appErr.ErrDatabase,
+			headers: delivery.Table{headerRetryCount: int32(2)},
+			ch:      &fakeChannel{},
+			keys:    []string{CreateQueue, "amq.gen-reply"},
+			retry:   2,
+			reply:   `{"error":"An error happened in database"}`,
+			code:    500,
+			acked:   1,
+		},
+		{
+			name:     "fail, message requeued when it cannot be moved",
+			err:      appErr.ErrDatabase,
+			ch:       &fakeChannel{err: errors.New("channel closed")},
+			requeued: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &fakeAcknowledger{}
+			deliv := &delivery.Delivery{
+				Acknowledger:  ack,
+				Headers:       tt.headers,
+				ReplyTo:       "amq.gen-reply",
+				CorrelationId: "42",
+				Body:          []byte(`{"salon_id": 1`),
+			}
+
+			errorSubscriber(CreateQueue, policy)(context.Background(), tt.err, deliv, tt.ch, &delivery.Publishing{})
+
+			assert.Equal(t, tt.keys, tt.ch.keys)
+			assert.Equal(t, tt.acked, ack.acked)
+			assert.Equal(t, tt.requeued, ack.requeued)
+			if len(tt.ch.published) == 0 {
+				return
+			}
+
+			if tt.keys[0] == deliv.ReplyTo {
+				require.Len(t, tt.ch.published, 1, "business errors are not dead-lettered")
+				assert.Equal(t, tt.code, tt.ch.published[0].Headers["code"])
+				assert.JSONEq(t, tt.reply, string(tt.ch.published[0].Body))
+				return
+			}
+
+			moved := tt.ch.published[0]
+			assert.Equal(t, deliv.Body, moved.Body)
+			assert.Equal(t, deliv.ReplyTo, moved.ReplyTo)
+			assert.Equal(t, deliv.CorrelationId, moved.CorrelationId)
+			assert.Equal(t, tt.retry, moved.Headers[headerRetryCount])
+			if tt.reply == "" {
+				assert.Len(t, tt.ch.published, 1)
+				return
+			}
+
+			assert.Equal(t, tt.err.Error(), moved.Headers[headerErrorReason])
+			assert.Equal(t, tt.code, moved.Headers[headerErrorCode])
+			assert.Equal(t, CreateQueue, moved.Headers[headerQueue])
+			require.Len(t, tt.ch.published, 2)
+			assert.Equal(t, tt.code, tt.ch.published[1].Headers["code"])
+			assert.JSONEq(t, tt.reply, string(tt.ch.published[1].Body))
+		})
+	}
+}
+
+func Test_decodeMakeAppointment(t *testing.T) {
 	type args struct {
-		ctx   context.Context
-		err   error
-		deliv *delivery.Delivery
-		ch    amqp.Channel
-		p     *delivery.Publishing
+		ctx context.Context
+		r   *delivery.Delivery
 	}
 	tests := []struct {
 		name string
 		args args
+		want interface{}
+		err  error
 	}{
 		{
-			name: "success, error found",
+			name: "success, make appointment sucefull",
 			args: args{
 				ctx: context.Background(),
-				err: appErr.ErrDatabase,
-				deliv: &delivery.Delivery{
+				r: &delivery.Delivery{
 					Body: []byte(`{
-						"user_id": 0,
-						"salon_id": 1,
-						"appointment_date": "2022-06-23T21:12:02.000000001Z"
+						"id": "628ed8e442c5ab8d69b6d4fa",
+						"user_id": 1
 					}`),
 				},
-				ch: &delivery.Channel{},
-				p: &delivery.Publishing{
+			},
+			want: model.MakeAppointment{ID: "628ed8e442c5ab8d69b6d4fa", UserID: 1},
+		},
+		{
+			name: "fail, cannot make appointment",
+			args: args{
+				ctx: context.Background(),
+				r: &delivery.Delivery{
 					Body: []byte(`{
-						"user_id": 0,
-						"salon_id": 1,
-						"appointment_date": "2022-06-23T21:12:02.000000001Z"
+						"user_id": 1
 					}`),
 				},
 			},
+			err: appErr.ErrInvalidBody,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			errorSubscriber(tt.args.ctx, tt.args.err, tt.args.deliv, tt.args.ch, tt.args.p)
+			got, err := decodeMakeAppointment(tt.args.ctx, tt.args.r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
-func Test_decodeMakeAppointment(t *testing.T) {
+func Test_decodeGenerateAppointments(t *testing.T) {
 	type args struct {
 		ctx context.Context
 		r   *delivery.Delivery
@@ -152,26 +361,35 @@ func Test_decodeMakeAppointment(t *testing.T) {
 		err  error
 	}{
 		{
-			name: "success, make appointment sucefull",
+			name: "success, decoded template",
 			args: args{
 				ctx: context.Background(),
 				r: &delivery.Delivery{
 					Body: []byte(`{
-						"id": "628ed8e442c5ab8d69b6d4fa",
-						"user_id": 1
-					}`),
+					"salon_id": 1,
+					"time_zone": "UTC",
+					"from": "2022-07-01",
+					"to": "2022-07-31",
+					"slot_minutes": 30,
+					"days": [{"weekday": "friday", "open": "09:00", "close": "18:00"}]
+				}`),
 				},
 			},
-			want: model.MakeAppointment{ID: "628ed8e442c5ab8d69b6d4fa", UserID: 1},
+			want: model.SlotTemplate{
+				SalonID:     1,
+				TimeZone:    "UTC",
+				From:        "2022-07-01",
+				To:          "2022-07-31",
+				SlotMinutes: 30,
+				Days:        []model.OpeningHours{{Weekday: "friday", Open: "09:00", Close: "18:00"}},
+			},
 		},
 		{
-			name: "fail, cannot make appointment",
+			name: "fail, missing opening days",
 			args: args{
 				ctx: context.Background(),
 				r: &delivery.Delivery{
-					Body: []byte(`{
-						"user_id": 1
-					}`),
+					Body: []byte(`{"salon_id": 1, "time_zone": "UTC", "from": "2022-07-01", "to": "2022-07-31", "slot_minutes": 30}`),
 				},
 			},
 			err: appErr.ErrInvalidBody,
@@ -179,9 +397,448 @@ func Test_decodeMakeAppointment(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := decodeMakeAppointment(tt.args.ctx, tt.args.r)
+			got, err := decodeGenerateAppointments(tt.args.ctx, tt.args.r)
 			assert.ErrorIs(t, err, tt.err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func Test_replyPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		deliv   delivery.Delivery
+		ch      *fakeChannel
+		keys    []string
+		acked   int
+		wantErr bool
+	}{
+		{
+			name:  "success, replied to the caller",
+			deliv: delivery.Delivery{ReplyTo: "amq.gen-reply", CorrelationId: "42"},
+			ch:    &fakeChannel{},
+			keys:  []string{"amq.gen-reply"},
+			acked: 1,
+		},
+		{
+			name:  "success, nothing to reply",
+			deliv: delivery.Delivery{},
+			ch:    &fakeChannel{},
+			acked: 1,
+		},
+		{
+			name:    "fail, reply not published",
+			deliv:   delivery.Delivery{ReplyTo: "amq.gen-reply", CorrelationId: "42"},
+			ch:      &fakeChannel{err: errors.New("channel closed")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &fakeAcknowledger{}
+			tt.deliv.Acknowledger = ack
+			p := &delivery.Publishing{Body: []byte(`{"id":"62b65300e1d7eab1ea9a681d"}`)}
+			err := replyPublisher(context.Background(), &tt.deliv, tt.ch, p)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.keys, tt.ch.keys)
+			assert.Equal(t, tt.acked, ack.acked)
+			for _, pub := range tt.ch.published {
+				assert.Equal(t, tt.deliv.CorrelationId, pub.CorrelationId)
+				assert.Equal(t, "application/json", pub.ContentType)
+				assert.Equal(t, p.Body, pub.Body)
+			}
+		})
+	}
+}
+
+func TestSubscriberRequestReply(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	book := model.MakeAppointment{ID: "62b65300e1d7eab1ea9a681d", UserID: 1}
+	response := model.AppResponse{
+		ID:              book.ID,
+		UserID:          1,
+		SalonID:         1,
+		AppointmentDate: time.Date(2022, time.June, 23, 21, 12, 02, 1, time.UTC),
+		Status:          model.StatusBooked,
+		Version:         2,
+	}
+	tests := []struct {
+		name string
+		init func(s *service.MockAppointmentServiceI)
+		body string
+		code int32
+	}{
+		{
+			name: "success, replied with the appointment",
+			init: func(s *service.MockAppointmentServiceI) {
+				s.EXPECT().MakeAppointment(gomock.Any(), book).Return(&response, nil)
+			},
+			body: `{
+				"id": "62b65300e1d7eab1ea9a681d",
+				"user_id": 1,
+				"salon_id": 1,
+				"appointment_date": "2022-06-23T21:12:02.000000001Z",
+				"end_date": "0001-01-01T00:00:00Z",
+				"duration_minutes": 0,
+				"status": "booked",
+				"version": 2
+			}`,
+			code: 200,
+		},
+		{
+			name: "success, replied with the error",
+			init: func(s *service.MockAppointmentServiceI) {
+				s.EXPECT().MakeAppointment(gomock.Any(), book).Return(nil, appErr.ErrAlreadyBooked)
+			},
+			body: `{"error":"Appointment already booked"}`,
+			code: 409,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewMockAppointmentServiceI(ctrl)
+			tt.init(svc)
+			ch := &fakeChannel{}
+			ack := &fakeAcknowledger{}
+			serve := amqp.NewSubscriber(
+				appointments.MakeAppointmentByUser(svc),
+				decodeMakeAppointment,
+				encodeResponseFunc,
+				subscriberOptions(MakeQueue, DefaultRetryPolicy)...,
+			).ServeDelivery(ch)
+
+			serve(&delivery.Delivery{
+				Acknowledger:  ack,
+				ReplyTo:       "amq.gen-reply",
+				CorrelationId: "42",
+				Body:          []byte(`{"id":"62b65300e1d7eab1ea9a681d","user_id":1}`),
+			})
+
+			require.NotEmpty(t, ch.published)
+			replied := ch.published[len(ch.published)-1]
+			assert.Equal(t, "amq.gen-reply", ch.keys[len(ch.keys)-1])
+			assert.Equal(t, "42", replied.CorrelationId)
+			assert.Equal(t, tt.code, replied.Headers["code"])
+			assert.JSONEq(t, tt.body, string(replied.Body))
+			assert.Equal(t, 1, ack.acked)
+		})
+	}
+}
+
+func TestNewBroker(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	topology := DefaultTopology
+	topology.ConsumerTag = "appointments"
+	tags := make([]string, 0, len(topology.Queues()))
+	for _, queue := range topology.Queues() {
+		tags = append(tags, "appointments."+queue)
+	}
+	book := model.MakeAppointment{ID: "62b65300e1d7eab1ea9a681d", UserID: 1}
+
+	t.Run("success, handled the in-flight delivery before stopping", func(t *testing.T) {
+		svc := service.NewMockAppointmentServiceI(ctrl)
+		ch := &fakeConsumer{}
+		ack := &fakeAcknowledger{}
+		ctx, cancel := context.WithCancel(context.Background())
+		handled := make(chan struct{})
+		svc.EXPECT().MakeAppointment(gomock.Any(), book).DoAndReturn(
+			func(context.Context, model.MakeAppointment) (*model.AppResponse, error) {
+				close(handled)
+				cancel()
+				return &model.AppResponse{ID: book.ID}, nil
+			})
+
+		errc := make(chan error, 1)
+		go func() { errc <- NewBroker(ctx, svc, nil, ch, topology) }()
+		require.Eventually(t, func() bool {
+			ch.mu.Lock()
+			defer ch.mu.Unlock()
+			return len(ch.deliveries) == len(tags)
+		}, time.Second, time.Millisecond)
+
+		ch.mu.Lock()
+		ch.deliveries["appointments."+MakeQueue] <- delivery.Delivery{
+			Acknowledger: ack,
+			Body:         []byte(`{"id":"62b65300e1d7eab1ea9a681d","user_id":1}`),
+		}
+		ch.mu.Unlock()
+
+		<-handled
+		select {
+		case err := <-errc:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("broker did not stop")
+		}
+		assert.Equal(t, 1, ack.acked)
+		assert.ElementsMatch(t, tags, ch.cancelled)
+	})
+
+	t.Run("success, returned when the deliveries were closed", func(t *testing.T) {
+		svc := service.NewMockAppointmentServiceI(ctrl)
+		ch := &fakeConsumer{}
+		errc := make(chan error, 1)
+		go func() { errc <- NewBroker(context.Background(), svc, nil, ch, topology) }()
+		require.Eventually(t, func() bool {
+			ch.mu.Lock()
+			defer ch.mu.Unlock()
+			return len(ch.deliveries) == len(tags)
+		}, time.Second, time.Millisecond)
+
+		ch.mu.Lock()
+		for _, deliveries := range ch.deliveries {
+			close(deliveries)
+		}
+		ch.mu.Unlock()
+
+		select {
+		case err := <-errc:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("broker did not stop")
+		}
+		assert.Empty(t, ch.cancelled)
+	})
+}
+
+func Test_decodeUpdateAppointment(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, update appointment",
+			body: `{
+				"id": "628ed8e442c5ab8d69b6d4fa",
+				"salon_id": 1,
+				"appointment_date": "2022-06-23T21:12:02Z",
+				"version": 3
+			}`,
+			want: model.UpsertAppointment{
+				ID:              "628ed8e442c5ab8d69b6d4fa",
+				SalonID:         1,
+				AppointmentDate: time.Date(2022, time.June, 23, 21, 12, 02, 0, time.UTC),
+				Version:         3,
+			},
+		},
+		{
+			name: "fail, id is required",
+			body: `{"salon_id": 1, "appointment_date": "2022-06-23T21:12:02Z"}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, version is required",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "salon_id": 1, "appointment_date": "2022-06-23T21:12:02Z"}`,
+			err:  appErr.ErrPreconditionRequired,
+		},
+		{
+			name: "fail, negative version",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "salon_id": 1, "appointment_date": "2022-06-23T21:12:02Z", "version": -1}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, salon_id is required",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "appointment_date": "2022-06-23T21:12:02Z"}`,
+			err:  appErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeUpdateAppointment(context.Background(), &delivery.Delivery{Body: []byte(tt.body)})
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeDeleteAppointment(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, delete appointment",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "version": 2}`,
+			want: model.DeleteAppointment{ID: "628ed8e442c5ab8d69b6d4fa", Version: 2},
+		},
+		{
+			name: "success, delete appointment stored before versions",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "version": 0}`,
+			want: model.DeleteAppointment{ID: "628ed8e442c5ab8d69b6d4fa"},
+		},
+		{
+			name: "fail, version is required",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa"}`,
+			err:  appErr.ErrPreconditionRequired,
+		},
+		{
+			name: "fail, id is required",
+			body: `{}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, invalid json",
+			body: `{"id": `,
+			err:  appErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeDeleteAppointment(context.Background(), &delivery.Delivery{Body: []byte(tt.body)})
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeCancelAppointment(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, cancel appointment",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "user_id": 1, "version": 2}`,
+			want: model.CancelAppointment{ID: "628ed8e442c5ab8d69b6d4fa", UserID: 1, Version: 2},
+		},
+		{
+			name: "fail, version is required",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "user_id": 1}`,
+			err:  appErr.ErrPreconditionRequired,
+		},
+		{
+			name: "fail, negative version",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa", "user_id": 1, "version": -1}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, user_id is required",
+			body: `{"id": "628ed8e442c5ab8d69b6d4fa"}`,
+			err:  appErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCancelAppointment(context.Background(), &delivery.Delivery{Body: []byte(tt.body)})
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeFindAppointmentsBySalon(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, find appointments by salon",
+			body: `{"id": 1, "limit": 10, "sort": "-appointment_date"}`,
+			want: model.FindAppBySalon{
+				ID:          1,
+				ListOptions: model.ListOptions{Limit: 10, Sort: "-appointment_date"},
+			},
+		},
+		{
+			name: "fail, id must be positive",
+			body: `{"limit": 10}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, limit over the maximum",
+			body: `{"id": 1, "limit": 1000}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, to before from",
+			body: `{"id": 1, "from": "2022-06-23T00:00:00Z", "to": "2022-06-22T00:00:00Z"}`,
+			err:  appErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, invalid page token",
+			body: `{"id": 1, "page": "not-a-token"}`,
+			err:  appErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeFindAppointmentsBySalon(context.Background(), &delivery.Delivery{Body: []byte(tt.body)})
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeAvailableAppointments(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, find available appointments",
+			body: `{"salon_id": [1, 2], "service_type": ["haircut"]}`,
+			want: model.FindAvailable{
+				SalonIDs:     []int{1, 2},
+				ServiceTypes: []model.ServiceType{model.ServiceHaircut},
+			},
+		},
+		{
+			name: "fail, invalid service type",
+			body: `{"service_type": ["massage"]}`,
+			err:  appErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeAvailableAppointments(context.Background(), &delivery.Delivery{Body: []byte(tt.body)})
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_deliveryIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		deliv delivery.Delivery
+		key   string
+		ok    bool
+	}{
+		{
+			name:  "header",
+			deliv: delivery.Delivery{MessageId: "message", Headers: delivery.Table{headerIdempotencyKey: "key"}},
+			key:   "key",
+			ok:    true,
+		},
+		{
+			name:  "message id",
+			deliv: delivery.Delivery{MessageId: "message"},
+			key:   "message",
+			ok:    true,
+		},
+		{
+			name:  "none",
+			deliv: delivery.Delivery{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := deliveryIdempotencyKey(context.Background(), nil, &tt.deliv)
+			key, ok := appointments.IdempotencyKey(ctx)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.key, key)
+		})
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"log"
 	stdHTTP "net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments"
 	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-kit/kit/transport/http"
@@ -19,22 +22,65 @@ import (
 
 var validate = validator.New()
 
-func NewHTTPHandler(svc service.AppointmentServiceI) stdHTTP.Handler {
+// HeaderIdempotencyKey is the header a client sets on a write request to
+// have it applied once, however many times it is sent.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// Operations served over HTTP only. They scope the idempotency keys of their
+// requests, as the queue names do for the other writes.
+const (
+	patchOperation    = "patch-appointment"
+	confirmOperation  = "confirm-appointment"
+	checkInOperation  = "check-in-appointment"
+	completeOperation = "complete-appointment"
+	noShowOperation   = "no-show-appointment"
+)
+
+func NewHTTPHandler(svc service.AppointmentServiceI, store repository.IdempotencyI) stdHTTP.Handler {
 	options := []http.ServerOption{
 		http.ServerErrorEncoder(errorHandler),
+		http.ServerBefore(idempotencyKey),
 	}
 
+	createApp := http.NewServer(
+		appointments.Idempotent(store, CreateQueue)(appointments.CreateAppointment(svc)),
+		decodeNewApp,
+		codeHTTP{201}.encodeAppResponse,
+		options...,
+	)
+
+	generateApp := http.NewServer(
+		appointments.Idempotent(store, GenerateQueue)(appointments.GenerateAppointments(svc)),
+		decodeGenerateApp,
+		codeHTTP{201}.encodeResponse,
+		options...,
+	)
+
+	bookApp := http.NewServer(
+		appointments.Idempotent(store, MakeQueue)(appointments.MakeAppointmentByUser(svc)),
+		decodeBookApp,
+		codeHTTP{200}.encodeAppResponse,
+		options...,
+	)
+
+	patchApp := http.NewServer(
+		appointments.Idempotent(store, patchOperation)(appointments.PatchAppointment(svc)),
+		decodePatchApp,
+		codeHTTP{200}.encodeAppResponse,
+		options...,
+	)
+
 	updateApp := http.NewServer(
-		appointments.UpdateAppointmentByUser(svc),
+		appointments.Idempotent(store, UpdateQueue)(appointments.UpdateAppointmentByUser(svc)),
 		decodeUpdateApp,
-		codeHTTP{200}.encodeResponse,
+		codeHTTP{200}.encodeAppResponse,
 		options...,
 	)
 
 	findAppByID := http.NewServer(
 		appointments.FindAppointmentByID(svc),
 		decodeFindAppByID,
-		codeHTTP{200}.encodeResponse,
+		codeHTTP{200}.encodeAppResponse,
 		options...,
 	)
 
@@ -59,6 +105,13 @@ func NewHTTPHandler(svc service.AppointmentServiceI) stdHTTP.Handler {
 		options...,
 	)
 
+	findAppByProfessionalID := http.NewServer(
+		appointments.FindAppointmentByProfessional(svc),
+		decodeAppByProfessional,
+		codeHTTP{200}.encodeResponse,
+		options...,
+	)
+
 	availableApp := http.NewServer(
 		appointments.AvailableAppointment(svc),
 		decodeAvailableApp,
@@ -67,27 +120,64 @@ func NewHTTPHandler(svc service.AppointmentServiceI) stdHTTP.Handler {
 	)
 
 	deleteApp := http.NewServer(
-		appointments.DeleteAppointment(svc),
+		appointments.Idempotent(store, DeleteQueue)(appointments.DeleteAppointment(svc)),
 		decodeDeleteApp,
 		codeHTTP{204}.encodeResponse,
 		options...,
 	)
 
 	cancelApp := http.NewServer(
-		appointments.CancelAppointment(svc),
+		appointments.Idempotent(store, CancelQueue)(appointments.CancelAppointment(svc)),
 		decodeCancelApp,
 		codeHTTP{204}.encodeResponse,
 		options...,
 	)
 
+	confirmApp := http.NewServer(
+		appointments.Idempotent(store, confirmOperation)(appointments.ChangeAppointmentStatus(svc)),
+		decodeConfirmApp,
+		codeHTTP{200}.encodeAppResponse,
+		options...,
+	)
+
+	checkInApp := http.NewServer(
+		appointments.Idempotent(store, checkInOperation)(appointments.ChangeAppointmentStatus(svc)),
+		decodeCheckInApp,
+		codeHTTP{200}.encodeAppResponse,
+		options...,
+	)
+
+	completeApp := http.NewServer(
+		appointments.Idempotent(store, completeOperation)(appointments.ChangeAppointmentStatus(svc)),
+		decodeCompleteApp,
+		codeHTTP{200}.encodeAppResponse,
+		options...,
+	)
+
+	noShowApp := http.NewServer(
+		appointments.Idempotent(store, noShowOperation)(appointments.ChangeAppointmentStatus(svc)),
+		decodeNoShowApp,
+		codeHTTP{200}.encodeAppResponse,
+		options...,
+	)
+
 	r := chi.NewRouter()
 
 	r.Get("/{id}", findAppByID.ServeHTTP)
 	r.Get("/", findAllApp.ServeHTTP)
 	r.Get("/user/{id}", findAppByUserID.ServeHTTP)
 	r.Get("/salon/{id}", findAppBySalonID.ServeHTTP)
+	r.Get("/professional/{id}", findAppByProfessionalID.ServeHTTP)
 	r.Get("/available", availableApp.ServeHTTP)
+	r.Post("/", createApp.ServeHTTP)
+	r.Post("/generate", generateApp.ServeHTTP)
+	r.Post("/{id}/book", bookApp.ServeHTTP)
+	r.Post("/{id}/confirm", confirmApp.ServeHTTP)
+	r.Post("/{id}/check-in", checkInApp.ServeHTTP)
+	r.Post("/{id}/complete", completeApp.ServeHTTP)
+	r.Post("/{id}/no-show", noShowApp.ServeHTTP)
 	r.Put("/{id}", updateApp.ServeHTTP)
+	r.Patch("/{id}", patchApp.ServeHTTP)
 	r.Put("/{id}/{user}", cancelApp.ServeHTTP)
 	r.Delete("/{id}", deleteApp.ServeHTTP)
 
@@ -102,11 +192,12 @@ func NewHTTPHandler(svc service.AppointmentServiceI) stdHTTP.Handler {
 // @Produce      json
 // @Failure      404  {string}  string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
-// @Failure      400  {string}  string "Cannot read path"
+// @Failure      400  {string}  string "Cannot read path or invalid appointment id"
 // @Success      200  {object}   model.AppResponse
 // @Param        id   path      string  true  "Appointment ID"
 // SchemaExample({\n"user_id": 1,\n"salon_id": 2,\n"appointment_date": "2022-06-23T21:12:02.000000001Z"\n})
-// @Router       /appointment/{id} [delete]
+// @Header       200  {string}  ETag  "Version of the appointment, to send as If-Match"
+// @Router       /appointment/{id} [get]
 func decodeFindAppByID(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
 	var app model.FindAppointmentsByIDRequest
 	if app.ID = chi.URLParam(r, "id"); app.ID == "" {
@@ -115,6 +206,95 @@ func decodeFindAppByID(_ context.Context, r *stdHTTP.Request) (interface{}, erro
 	return app, nil
 }
 
+// ShowAccount godoc
+// @Summary      Create an appointment
+// @Description  Create a new appointment slot for a salon
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Invalid body"
+// @Failure      409  {string} string "Appointment overlaps another slot, or a request with this idempotency key is in progress"
+// @Failure      422  {string} string "Appointment date must be in the future, or the idempotency key was used by another request"
+// @Success      201  {object}   model.AppResponse
+// @Param appointment body model.UpsertAppointment true "Appointment"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Router       /appointment [post]
+func decodeNewApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	var app model.UpsertAppointment
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		return nil, appErr.ErrInvalidBody
+	}
+
+	if err := validate.Struct(app); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	return app, nil
+}
+
+// ShowAccount godoc
+// @Summary      Generate appointments
+// @Description  Create the available slots of a salon from its weekly opening hours, skipping the slots that already started or overlap a stored one
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Invalid body"
+// @Success      201  {object}   model.GenerateResponse
+// @Param template body model.SlotTemplate true "Opening hours template"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      409  {string} string "A request with this idempotency key is in progress"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Router       /appointment/generate [post]
+func decodeGenerateApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	var template model.SlotTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		return nil, appErr.ErrInvalidBody
+	}
+
+	if err := validate.Struct(template); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	return template, nil
+}
+
+// ShowAccount godoc
+// @Summary      Book an appointment
+// @Description  Book an available appointment by ID for the user in the body
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Invalid body or invalid appointment id"
+// @Failure      409  {string} string "Appointment already booked, or a request with this idempotency key is in progress"
+// @Success      200  {object}   model.AppResponse
+// @Param        id   path      string  true  "Appointment ID"
+// @Param booking body model.BookAppointment true "Booking"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Router       /appointment/{id}/book [post]
+func decodeBookApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	var app model.MakeAppointment
+	if app.ID = chi.URLParam(r, "id"); app.ID == "" {
+		return nil, appErr.ErrInvalidPath
+	}
+
+	var book model.BookAppointment
+	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
+		return nil, appErr.ErrInvalidBody
+	}
+	app.UserID = book.UserID
+
+	if err := validate.Struct(app); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	return app, nil
+}
+
 // ShowAccount godoc
 // @Summary      Update an appointment
 // @Description  Get Appointment by ID and body for update
@@ -123,12 +303,19 @@ func decodeFindAppByID(_ context.Context, r *stdHTTP.Request) (interface{}, erro
 // @Produce      json
 // @Failure      404  {string} string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
-// @Failure      400  {string} string "Cannot read path"
+// @Failure      400  {string} string "Cannot read path or invalid appointment id"
+// @Failure      409  {string} string "Appointment overlaps another slot, or a request with this idempotency key is in progress"
+// @Failure      422  {string} string "Appointment date must be in the future, or the idempotency key was used by another request"
 // @Success      200  {object}   model.AppResponse
 // @Param        id   path      string  true  "Appointment ID"
 // @Param appointment body string true "Appointment"
 // SchemaExample({\n"user_id": 1,\n"salon_id": 2,\n"appointment_date": "2022-06-23T21:12:02.000000001Z"\n})
 // SchemaExample({\n"user_id": 1,\n"salon_id": 2,\n"appointment_date": "2022-06-23T21:12:02.000000001Z"\n})
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Param        If-Match  header  string  true  "ETag the appointment was read with"
+// @Failure      412  {string} string "Appointment was changed by someone else, read it again"
+// @Failure      428  {string} string "If-Match header is required"
+// @Header       200  {string}  ETag  "Version of the updated appointment"
 // @Router       /appointment/{id} [put]
 func decodeUpdateApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
 	var app model.UpsertAppointment
@@ -136,6 +323,11 @@ func decodeUpdateApp(_ context.Context, r *stdHTTP.Request) (interface{}, error)
 		return nil, appErr.ErrInvalidPath
 	}
 
+	version, err := ifMatch(r)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
 		return nil, appErr.ErrInvalidBody
 	}
@@ -144,9 +336,105 @@ func decodeUpdateApp(_ context.Context, r *stdHTTP.Request) (interface{}, error)
 		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
 	}
 
+	app.Version = version
 	return app, nil
 }
 
+// patchable maps the fields a patch may hold to the UpsertAppointment
+// fields validating them. Required fields cannot be cleared, and user_id is
+// left out as only booking and cancelling change it.
+var patchable = map[string]struct {
+	field    string
+	required bool
+}{
+	"salon_id":         {"SalonID", true},
+	"professional_id":  {"ProfessionalID", false},
+	"appointment_date": {"AppointmentDate", true},
+	"duration_minutes": {"DurationMinutes", false},
+	"service_type":     {"ServiceType", false},
+	"price_cents":      {"PriceCents", false},
+}
+
+// ShowAccount godoc
+// @Summary      Patch an appointment
+// @Description  Change some fields of an appointment with a JSON Merge Patch, null clears a field
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Invalid body or invalid appointment id"
+// @Failure      409  {string} string "Appointment overlaps another slot, or a request with this idempotency key is in progress"
+// @Failure      422  {string} string "Appointment date must be in the future, or the idempotency key was used by another request"
+// @Success      200  {object}   model.AppResponse
+// @Param        id   path      string  true  "Appointment ID"
+// @Param patch body object true "Fields to change"
+// SchemaExample({\n"appointment_date": "2022-06-23T21:12:02.000000001Z"\n})
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Param        If-Match  header  string  true  "ETag the appointment was read with"
+// @Failure      412  {string} string "Appointment was changed by someone else, read it again"
+// @Failure      428  {string} string "If-Match header is required"
+// @Header       200  {string}  ETag  "Version of the updated appointment"
+// @Router       /appointment/{id} [patch]
+func decodePatchApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	var (
+		patch model.PatchAppointment
+		err   error
+	)
+	if patch.ID = chi.URLParam(r, "id"); patch.ID == "" {
+		return nil, appErr.ErrInvalidPath
+	}
+
+	if patch.Version, err = ifMatch(r); err != nil {
+		return nil, err
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&patch.Fields); err != nil {
+		return nil, appErr.ErrInvalidBody
+	}
+
+	if len(patch.Fields) == 0 {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, "nothing to patch")
+	}
+
+	// Only the fields set are decoded and validated, the rest of the
+	// appointment is not known until the patch is applied.
+	set := make(map[string]json.RawMessage, len(patch.Fields))
+	fields := make([]string, 0, len(patch.Fields))
+	for name, value := range patch.Fields {
+		field, ok := patchable[name]
+		if !ok {
+			return nil, errors.Wrapf(appErr.ErrInvalidBody, "%s cannot be patched", name)
+		}
+
+		if patch.Clears(name) {
+			if field.required {
+				return nil, errors.Wrapf(appErr.ErrInvalidBody, "%s cannot be cleared", name)
+			}
+			continue
+		}
+
+		set[name] = value
+		fields = append(fields, field.field)
+	}
+
+	body, err := json.Marshal(set)
+	if err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	var partial model.UpsertAppointment
+	if err := json.Unmarshal(body, &partial); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	if err := validate.StructPartial(partial, fields...); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidBody, err.Error())
+	}
+
+	return patch, nil
+}
+
 // ShowAccount godoc
 // @Summary      Get all appointments
 // @Description  Get all appointments
@@ -155,10 +443,21 @@ func decodeUpdateApp(_ context.Context, r *stdHTTP.Request) (interface{}, error)
 // @Produce      json
 // @Failure      404  {string} string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
-// @Success      200  {array}   model.AppResponse
+// @Failure      400  {string} string "Invalid query parameters"
+// @Success      200  {object}   model.AppPageResponse
+// @Param        limit  query     int     false  "Page size, up to 100"  default(20)
+// @Param        page   query     string  false  "Token of the page, as returned in next_page"
+// @Param        from   query     string  false  "Only appointments at or after this date (RFC3339)"
+// @Param        to     query     string  false  "Only appointments before this date (RFC3339)"
+// @Param        sort   query     string  false  "Sort order"  Enums(appointment_date, -appointment_date)
 // @Router       /appointment [get]
 func decodeAllApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
-	return nil, nil
+	opts, err := decodeListOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
 }
 
 // ShowAccount godoc
@@ -170,8 +469,13 @@ func decodeAllApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
 // @Failure      404  {string} string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
 // @Failure      400  {string} string "Cannot read path"
-// @Success      200  {array}   model.AppResponse
+// @Success      200  {object}   model.AppPageResponse
 // @Param        id   path      int  true  "User ID"
+// @Param        limit  query     int     false  "Page size, up to 100"  default(20)
+// @Param        page   query     string  false  "Token of the page, as returned in next_page"
+// @Param        from   query     string  false  "Only appointments at or after this date (RFC3339)"
+// @Param        to     query     string  false  "Only appointments before this date (RFC3339)"
+// @Param        sort   query     string  false  "Sort order"  Enums(appointment_date, -appointment_date)
 // @Router       /appointment/user/{id} [get]
 func decodeAppByUser(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
 	var (
@@ -182,6 +486,10 @@ func decodeAppByUser(_ context.Context, r *stdHTTP.Request) (interface{}, error)
 		return nil, appErr.ErrInvalidPath
 	}
 
+	if app.ListOptions, err = decodeListOptions(r); err != nil {
+		return nil, err
+	}
+
 	return app, nil
 }
 
@@ -194,8 +502,13 @@ func decodeAppByUser(_ context.Context, r *stdHTTP.Request) (interface{}, error)
 // @Failure      404  {string} string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
 // @Failure      400  {string} string "Cannot read path"
-// @Success      200  {array}   model.AppResponse
+// @Success      200  {object}   model.AppPageResponse
 // @Param        id   path      int  true  "Salon ID"
+// @Param        limit  query     int     false  "Page size, up to 100"  default(20)
+// @Param        page   query     string  false  "Token of the page, as returned in next_page"
+// @Param        from   query     string  false  "Only appointments at or after this date (RFC3339)"
+// @Param        to     query     string  false  "Only appointments before this date (RFC3339)"
+// @Param        sort   query     string  false  "Sort order"  Enums(appointment_date, -appointment_date)
 // @Router       /appointment/salon/{id} [get]
 func decodeAppBySalon(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
 	var (
@@ -206,6 +519,43 @@ func decodeAppBySalon(_ context.Context, r *stdHTTP.Request) (interface{}, error
 		return nil, appErr.ErrInvalidPath
 	}
 
+	if app.ListOptions, err = decodeListOptions(r); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// ShowAccount godoc
+// @Summary      Get appointments by professional id
+// @Description  get by professional ID and return an appointment
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Cannot read path"
+// @Success      200  {object}   model.AppPageResponse
+// @Param        id   path      int  true  "Professional ID"
+// @Param        limit  query     int     false  "Page size, up to 100"  default(20)
+// @Param        page   query     string  false  "Token of the page, as returned in next_page"
+// @Param        from   query     string  false  "Only appointments at or after this date (RFC3339)"
+// @Param        to     query     string  false  "Only appointments before this date (RFC3339)"
+// @Param        sort   query     string  false  "Sort order"  Enums(appointment_date, -appointment_date)
+// @Router       /appointment/professional/{id} [get]
+func decodeAppByProfessional(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	var (
+		app model.FindAppByProfessional
+		err error
+	)
+	if app.ID, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
+		return nil, appErr.ErrInvalidPath
+	}
+
+	if app.ListOptions, err = decodeListOptions(r); err != nil {
+		return nil, err
+	}
+
 	return app, nil
 }
 
@@ -217,30 +567,79 @@ func decodeAppBySalon(_ context.Context, r *stdHTTP.Request) (interface{}, error
 // @Produce      json
 // @Failure      404  {string}  string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
-// @Failure      400  {string}  string "Cannot read path"
+// @Failure      400  {string}  string "Cannot read path or invalid appointment id"
 // @Success      204
 // @Param        id   path      string  true  "Appointment ID"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      409  {string} string "A request with this idempotency key is in progress"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Param        If-Match  header  string  true  "ETag the appointment was read with"
+// @Failure      412  {string} string "Appointment was changed by someone else, read it again"
+// @Failure      428  {string} string "If-Match header is required"
 // @Router       /appointment/{id} [delete]
 func decodeDeleteApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
-	var app model.DeleteAppointment
+	var (
+		app model.DeleteAppointment
+		err error
+	)
 	if app.ID = chi.URLParam(r, "id"); app.ID == "" {
 		return nil, appErr.ErrInvalidPath
 	}
+
+	if app.Version, err = ifMatch(r); err != nil {
+		return nil, err
+	}
 	return app, nil
 }
 
 // ShowAccount godoc
 // @Summary      Get available appointments
-// @Description  get all available appointments
+// @Description  get the available appointments that have not started yet, ordered by date
 // @Tags         appointment
 // @Accept       json
 // @Produce      json
 // @Failure      404  {string} string "Appointment not found"
 // @Failure      500  {string} string "An error happened in database"
-// @Success      200  {array}   model.AppResponse
+// @Failure      400  {string} string "Invalid query parameters"
+// @Success      200  {object}   model.AppPageResponse
+// @Param        salon_id  query  []int  false  "Salon IDs, repeated or comma separated"  collectionFormat(csv)
+// @Param        service_type  query  []string  false  "Service types, repeated or comma separated"  collectionFormat(csv)  Enums(haircut, manicure, coloring)
+// @Param        limit  query     int     false  "Page size, up to 100"  default(20)
+// @Param        page   query     string  false  "Token of the page, as returned in next_page"
+// @Param        from   query     string  false  "Only appointments at or after this date (RFC3339)"
+// @Param        to     query     string  false  "Only appointments before this date (RFC3339)"
+// @Param        sort   query     string  false  "Sort order"  Enums(appointment_date, -appointment_date)
 // @Router       /appointment/available [get]
 func decodeAvailableApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
-	return nil, nil
+	var (
+		app model.FindAvailable
+		err error
+	)
+	for _, param := range r.URL.Query()["salon_id"] {
+		for _, id := range strings.Split(param, ",") {
+			salon, err := strconv.Atoi(strings.TrimSpace(id))
+			if err != nil {
+				return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+			}
+			app.SalonIDs = append(app.SalonIDs, salon)
+		}
+	}
+
+	for _, param := range r.URL.Query()["service_type"] {
+		for _, service := range strings.Split(param, ",") {
+			app.ServiceTypes = append(app.ServiceTypes, model.ServiceType(strings.TrimSpace(service)))
+		}
+	}
+
+	if err = validate.Struct(app); err != nil {
+		return nil, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+	}
+
+	if app.ListOptions, err = decodeListOptions(r); err != nil {
+		return nil, err
+	}
+
+	return app, nil
 }
 
 // ShowAccount godoc
@@ -249,16 +648,23 @@ func decodeAvailableApp(_ context.Context, r *stdHTTP.Request) (interface{}, err
 // @Tags         appointment
 // @Accept       json
 // @Produce      json
-// @Failure      400  {object} string "Cannot read path"
+// @Failure      400  {object} string "Cannot read path or invalid appointment id"
+// @Failure      403  {string} string "Appointment belongs to another user"
 // @Failure      404  {object} string "Appointment not found"
+// @Failure      409  {string} string "Invalid appointment status transition, or a request with this idempotency key is in progress"
 // @Failure      500  {string} string "An error happened in database"
 // @Success      204
 // @Param        id   path      string  true  "Appointment ID"
 // @Param        user   path      string  true  "User ID"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Param        If-Match  header  string  true  "ETag the appointment was read with"
+// @Failure      412  {string} string "Appointment was changed by someone else, read it again"
+// @Failure      428  {string} string "If-Match header is required"
 // @Router       /appointment/{id}/{user} [put]
 func decodeCancelApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
 	var (
-		app model.MakeAppointment
+		app model.CancelAppointment
 		err error
 	)
 	if app.ID = chi.URLParam(r, "id"); app.ID == "" {
@@ -269,13 +675,216 @@ func decodeCancelApp(_ context.Context, r *stdHTTP.Request) (interface{}, error)
 		return nil, appErr.ErrInvalidPath
 	}
 
+	if app.Version, err = ifMatch(r); err != nil {
+		return nil, err
+	}
 	return app, nil
 }
 
+// ShowAccount godoc
+// @Summary      Confirm an appointment
+// @Description  Confirm a booked appointment by ID
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      409  {string} string "Invalid appointment status transition, or a request with this idempotency key is in progress"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Cannot read path or invalid appointment id"
+// @Success      200  {object}   model.AppResponse
+// @Param        id   path      string  true  "Appointment ID"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Router       /appointment/{id}/confirm [post]
+func decodeConfirmApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	return decodeChangeStatus(r, model.StatusConfirmed)
+}
+
+// ShowAccount godoc
+// @Summary      Check in an appointment
+// @Description  Mark that the user of an appointment arrived at the salon
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      409  {string} string "Invalid appointment status transition, or a request with this idempotency key is in progress"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Cannot read path or invalid appointment id"
+// @Success      200  {object}   model.AppResponse
+// @Param        id   path      string  true  "Appointment ID"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Router       /appointment/{id}/check-in [post]
+func decodeCheckInApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	return decodeChangeStatus(r, model.StatusCheckedIn)
+}
+
+// ShowAccount godoc
+// @Summary      Complete an appointment
+// @Description  Mark a checked-in appointment as completed
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      409  {string} string "Invalid appointment status transition, or a request with this idempotency key is in progress"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Cannot read path or invalid appointment id"
+// @Success      200  {object}   model.AppResponse
+// @Param        id   path      string  true  "Appointment ID"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Router       /appointment/{id}/complete [post]
+func decodeCompleteApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	return decodeChangeStatus(r, model.StatusCompleted)
+}
+
+// ShowAccount godoc
+// @Summary      Mark an appointment as no-show
+// @Description  Mark that the user of a booked appointment did not show up
+// @Tags         appointment
+// @Accept       json
+// @Produce      json
+// @Failure      404  {string} string "Appointment not found"
+// @Failure      409  {string} string "Invalid appointment status transition, or a request with this idempotency key is in progress"
+// @Failure      500  {string} string "An error happened in database"
+// @Failure      400  {string} string "Cannot read path or invalid appointment id"
+// @Success      200  {object}   model.AppResponse
+// @Param        id   path      string  true  "Appointment ID"
+// @Param        Idempotency-Key  header  string  false  "Applies the request once per key, a request sent again gets the first response"
+// @Failure      422  {string} string "Idempotency key was used by another request"
+// @Router       /appointment/{id}/no-show [post]
+func decodeNoShowApp(_ context.Context, r *stdHTTP.Request) (interface{}, error) {
+	return decodeChangeStatus(r, model.StatusNoShow)
+}
+
+func decodeChangeStatus(r *stdHTTP.Request, status model.Status) (interface{}, error) {
+	app := model.ChangeStatus{Status: status}
+	if app.ID = chi.URLParam(r, "id"); app.ID == "" {
+		return nil, appErr.ErrInvalidPath
+	}
+
+	return app, nil
+}
+
+// decodeListOptions reads the pagination, date range and sort query
+// parameters shared by every list route.
+func decodeListOptions(r *stdHTTP.Request) (model.ListOptions, error) {
+	var (
+		opts  model.ListOptions
+		err   error
+		query = r.URL.Query()
+	)
+	if limit := query.Get("limit"); limit != "" {
+		if opts.Limit, err = strconv.Atoi(limit); err != nil {
+			return opts, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+	}
+
+	if opts.Page = query.Get("page"); opts.Page != "" {
+		if _, err := model.DecodePageToken(opts.Page); err != nil {
+			return opts, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+	}
+
+	if from := query.Get("from"); from != "" {
+		if opts.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return opts, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+	}
+
+	if to := query.Get("to"); to != "" {
+		if opts.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return opts, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+		}
+	}
+
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.To.After(opts.From) {
+		return opts, errors.Wrap(appErr.ErrInvalidQuery, "to must be after from")
+	}
+
+	opts.Sort = query.Get("sort")
+	if err := validate.Struct(opts); err != nil {
+		return opts, errors.Wrap(appErr.ErrInvalidQuery, err.Error())
+	}
+
+	return opts, nil
+}
+
 type codeHTTP struct {
 	int
 }
 
+// etag is the entity tag of an appointment at version.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatch returns the version a write applies to, from the If-Match header
+// holding the ETag the appointment was read with, or model.AnyVersion for *.
+// If-Match compares tags the strong way (RFC 9110, section 13.1.1), so a
+// weak W/"..." tag never matches, and the tags listed must all name the
+// same version. Any other tag cannot match the current version.
+func ifMatch(r *stdHTTP.Request) (int64, error) {
+	field := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if field == "" {
+		return 0, appErr.ErrPreconditionRequired
+	}
+
+	if field == "*" {
+		return model.AnyVersion, nil
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(field, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return 0, errors.Wrapf(appErr.ErrVersionMismatch, "invalid entity tag %s", tag)
+		}
+
+		version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 63)
+		if err != nil {
+			return 0, errors.Wrapf(appErr.ErrVersionMismatch, "invalid entity tag %s", tag)
+		}
+		if len(versions) > 0 && versions[0] != int64(version) {
+			return 0, errors.Wrapf(appErr.ErrVersionMismatch, "entity tags of different versions %s", field)
+		}
+		versions = append(versions, int64(version))
+	}
+
+	if len(versions) == 0 {
+		return 0, errors.Wrapf(appErr.ErrVersionMismatch, "weak entity tags never match %s", field)
+	}
+
+	return versions[0], nil
+}
+
+func idempotencyKey(ctx context.Context, r *stdHTTP.Request) context.Context {
+	return appointments.WithIdempotencyKey(ctx, r.Header.Get(HeaderIdempotencyKey))
+}
+
+// encodeAppResponse encodes a single appointment along with its ETag. A
+// replayed idempotent response is the appointment as JSON, its ETag is
+// taken from the version it holds.
+func (c codeHTTP) encodeAppResponse(ctx context.Context, w stdHTTP.ResponseWriter, input interface{}) error {
+	switch app := input.(type) {
+	case *model.AppResponse:
+		w.Header().Set("ETag", etag(app.Version))
+	case json.RawMessage:
+		var replayed struct {
+			Version *int64 `json:"version"`
+		}
+		if err := json.Unmarshal(app, &replayed); err == nil && replayed.Version != nil {
+			w.Header().Set("ETag", etag(*replayed.Version))
+		}
+	}
+
+	return c.encodeResponse(ctx, w, input)
+}
+
 func (c codeHTTP) encodeResponse(_ context.Context, w stdHTTP.ResponseWriter, input interface{}) error {
 	w.Header().Set("Content-type", "application/json; charset=UTF-8")
 	w.WriteHeader(c.int)
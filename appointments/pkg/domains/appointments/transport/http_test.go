@@ -2,15 +2,20 @@ package transport
 
 import (
 	"context"
+	"encoding/json"
 	stdHTTP "net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments"
 	apErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
 	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/model"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/repository"
+	"github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/service"
 	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,6 +105,7 @@ func Test_decodeUpdateApp(t *testing.T) {
 				),
 			},
 			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				r.Header.Set("If-Match", `"3"`)
 				chiCtx := chi.NewRouteContext()
 				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
 				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
@@ -109,8 +115,26 @@ func Test_decodeUpdateApp(t *testing.T) {
 				UserID:          0,
 				SalonID:         1,
 				AppointmentDate: time.Date(2022, time.June, 23, 21, 12, 02, 1, time.UTC),
+				Version:         3,
 			},
 		},
+		{
+			name: "fail, without If-Match",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"PUT",
+					"/"+"628ed8e442c5ab8d69b6d4fa",
+					strings.NewReader(`{"salon_id": 1, "appointment_date": "2022-06-23T21:12:02.000000001Z"}`),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			err: apErr.ErrPreconditionRequired,
+		},
 		{
 			name: "fail, cannot decodified new update app",
 			args: args{
@@ -166,6 +190,58 @@ func Test_decodeAllApp(t *testing.T) {
 					strings.NewReader(``),
 				),
 			},
+			want: model.ListOptions{},
+		},
+		{
+			name: "success, decodified page, date range and sort",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"GET",
+					"/?limit=10&from=2022-06-01T00:00:00Z&to=2022-07-01T00:00:00Z&sort=-appointment_date&page="+
+						model.EncodePageToken(model.Appointment{ID: "628ed8e442c5ab8d69b6d4fa"}),
+					nil,
+				),
+			},
+			want: model.ListOptions{
+				Limit: 10,
+				Page:  model.EncodePageToken(model.Appointment{ID: "628ed8e442c5ab8d69b6d4fa"}),
+				From:  time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC),
+				To:    time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC),
+				Sort:  model.SortDateDesc,
+			},
+		},
+		{
+			name: "fail, invalid limit",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/?limit=1000", nil),
+			},
+			err: apErr.ErrInvalidQuery,
+		},
+		{
+			name: "fail, invalid sort",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/?sort=salon_id", nil),
+			},
+			err: apErr.ErrInvalidQuery,
+		},
+		{
+			name: "fail, invalid page token",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/?page=not-a-token", nil),
+			},
+			err: apErr.ErrInvalidQuery,
+		},
+		{
+			name: "fail, to before from",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/?from=2022-07-01T00:00:00Z&to=2022-06-01T00:00:00Z", nil),
+			},
+			err: apErr.ErrInvalidQuery,
 		},
 	}
 	for _, tt := range tests {
@@ -303,6 +379,69 @@ func Test_decodeAppBySalon(t *testing.T) {
 	}
 }
 
+func Test_decodeAppByProfessional(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   *stdHTTP.Request
+	}
+	tests := []struct {
+		name string
+		args args
+		want interface{}
+		init func(r *stdHTTP.Request) *stdHTTP.Request
+		err  error
+	}{
+		{
+			name: "success, decodified new find app by professional",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"GET",
+					"/1",
+					strings.NewReader(
+						`{}`,
+					),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "1")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			want: model.FindAppByProfessional{
+				ID: 1,
+			},
+		},
+		{
+			name: "fail, cannot read id in the path",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"GET",
+					"/1",
+					strings.NewReader(
+						`{}`,
+					),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			err: apErr.ErrInvalidPath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.init(tt.args.r)
+			got, err := decodeAppByProfessional(tt.args.ctx, r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func Test_decodeDeleteApp(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -326,12 +465,30 @@ func Test_decodeDeleteApp(t *testing.T) {
 				),
 			},
 			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				r.Header.Set("If-Match", `"0"`)
 				chiCtx := chi.NewRouteContext()
 				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
 				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
 			},
 			want: model.DeleteAppointment{ID: "628ed8e442c5ab8d69b6d4fa"},
 		},
+		{
+			name: "fail, without If-Match",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"DELETE",
+					"/628ed8e442c5ab8d69b6d4fa",
+					strings.NewReader(`{}`),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			err: apErr.ErrPreconditionRequired,
+		},
 		{
 			name: "fail, cannot decodified new delete appointment",
 			args: args{
@@ -381,6 +538,51 @@ func Test_decodeAvailableApp(t *testing.T) {
 					strings.NewReader(``),
 				),
 			},
+			want: model.FindAvailable{},
+		},
+		{
+			name: "success, decodified salons and date range",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"GET",
+					"/available?salon_id=1,2&salon_id=3&from=2022-06-01T00:00:00Z&to=2022-07-01T00:00:00Z",
+					nil,
+				),
+			},
+			want: model.FindAvailable{
+				SalonIDs: []int{1, 2, 3},
+				ListOptions: model.ListOptions{
+					From: time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC),
+					To:   time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "success, decodified service types",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/available?service_type=haircut,manicure&service_type=coloring", nil),
+			},
+			want: model.FindAvailable{
+				ServiceTypes: []model.ServiceType{model.ServiceHaircut, model.ServiceManicure, model.ServiceColoring},
+			},
+		},
+		{
+			name: "fail, invalid salon id",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/available?salon_id=abc", nil),
+			},
+			err: apErr.ErrInvalidQuery,
+		},
+		{
+			name: "fail, unknown service type",
+			args: args{
+				ctx: context.Background(),
+				r:   httptest.NewRequest("GET", "/available?service_type=massage", nil),
+			},
+			err: apErr.ErrInvalidQuery,
 		},
 	}
 	for _, tt := range tests {
@@ -418,9 +620,28 @@ func Test_decodeCancelApp(t *testing.T) {
 				chiCtx := chi.NewRouteContext()
 				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
 				chiCtx.URLParams.Add("user", "1")
+				r.Header.Set("If-Match", `"2"`)
 				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
 			},
-			want: model.MakeAppointment{ID: "628ed8e442c5ab8d69b6d4fa", UserID: 1},
+			want: model.CancelAppointment{ID: "628ed8e442c5ab8d69b6d4fa", UserID: 1, Version: 2},
+		},
+		{
+			name: "fail, without If-Match",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"PUT",
+					"/{id}/{user}",
+					strings.NewReader(`{}`),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
+				chiCtx.URLParams.Add("user", "1")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			err: apErr.ErrPreconditionRequired,
 		},
 		{
 			name: "fail, empty user id",
@@ -468,3 +689,544 @@ func Test_decodeCancelApp(t *testing.T) {
 		})
 	}
 }
+
+func Test_decodeNewApp(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   *stdHTTP.Request
+	}
+	tests := []struct {
+		name string
+		args args
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, decodified new app",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/",
+					strings.NewReader(
+						`{
+							"salon_id": 1,
+							"appointment_date": "2022-06-23T21:12:02.000000001Z"
+						}`,
+					),
+				),
+			},
+			want: model.UpsertAppointment{
+				SalonID:         1,
+				AppointmentDate: time.Date(2022, time.June, 23, 21, 12, 02, 1, time.UTC),
+			},
+		},
+		{
+			name: "fail, invalid json",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/",
+					strings.NewReader(`{"salon_id": 1`),
+				),
+			},
+			err: apErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, missing salon id",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/",
+					strings.NewReader(`{"appointment_date": "2022-06-23T21:12:02.000000001Z"}`),
+				),
+			},
+			err: apErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeNewApp(tt.args.ctx, tt.args.r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeBookApp(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   *stdHTTP.Request
+	}
+	tests := []struct {
+		name string
+		args args
+		init func(r *stdHTTP.Request) *stdHTTP.Request
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, decode book",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/{id}/book",
+					strings.NewReader(`{"user_id": 1}`),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			want: model.MakeAppointment{ID: "628ed8e442c5ab8d69b6d4fa", UserID: 1},
+		},
+		{
+			name: "fail, empty id",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/{id}/book",
+					strings.NewReader(`{"user_id": 1}`),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			err: apErr.ErrInvalidPath,
+		},
+		{
+			name: "fail, missing user id",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/{id}/book",
+					strings.NewReader(`{}`),
+				),
+			},
+			init: func(r *stdHTTP.Request) *stdHTTP.Request {
+				chiCtx := chi.NewRouteContext()
+				chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
+				return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			},
+			err: apErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.init(tt.args.r)
+			got, err := decodeBookApp(tt.args.ctx, r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeChangeStatus(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   *stdHTTP.Request
+	}
+	withID := func(id string) func(r *stdHTTP.Request) *stdHTTP.Request {
+		return func(r *stdHTTP.Request) *stdHTTP.Request {
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", id)
+			return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+		}
+	}
+	tests := []struct {
+		name   string
+		args   args
+		init   func(r *stdHTTP.Request) *stdHTTP.Request
+		decode func(context.Context, *stdHTTP.Request) (interface{}, error)
+		want   interface{}
+		err    error
+	}{
+		{
+			name:   "success, decode confirm",
+			args:   args{ctx: context.Background(), r: httptest.NewRequest("POST", "/{id}/confirm", nil)},
+			init:   withID("628ed8e442c5ab8d69b6d4fa"),
+			decode: decodeConfirmApp,
+			want:   model.ChangeStatus{ID: "628ed8e442c5ab8d69b6d4fa", Status: model.StatusConfirmed},
+		},
+		{
+			name:   "success, decode check in",
+			args:   args{ctx: context.Background(), r: httptest.NewRequest("POST", "/{id}/check-in", nil)},
+			init:   withID("628ed8e442c5ab8d69b6d4fa"),
+			decode: decodeCheckInApp,
+			want:   model.ChangeStatus{ID: "628ed8e442c5ab8d69b6d4fa", Status: model.StatusCheckedIn},
+		},
+		{
+			name:   "success, decode complete",
+			args:   args{ctx: context.Background(), r: httptest.NewRequest("POST", "/{id}/complete", nil)},
+			init:   withID("628ed8e442c5ab8d69b6d4fa"),
+			decode: decodeCompleteApp,
+			want:   model.ChangeStatus{ID: "628ed8e442c5ab8d69b6d4fa", Status: model.StatusCompleted},
+		},
+		{
+			name:   "success, decode no show",
+			args:   args{ctx: context.Background(), r: httptest.NewRequest("POST", "/{id}/no-show", nil)},
+			init:   withID("628ed8e442c5ab8d69b6d4fa"),
+			decode: decodeNoShowApp,
+			want:   model.ChangeStatus{ID: "628ed8e442c5ab8d69b6d4fa", Status: model.StatusNoShow},
+		},
+		{
+			name:   "fail, empty id",
+			args:   args{ctx: context.Background(), r: httptest.NewRequest("POST", "/{id}/confirm", nil)},
+			init:   withID(""),
+			decode: decodeConfirmApp,
+			err:    apErr.ErrInvalidPath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.init(tt.args.r)
+			got, err := tt.decode(tt.args.ctx, r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_decodeGenerateApp(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		r   *stdHTTP.Request
+	}
+	tests := []struct {
+		name string
+		args args
+		want interface{}
+		err  error
+	}{
+		{
+			name: "success, decodified template",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/generate",
+					strings.NewReader(
+						`{
+							"salon_id": 1,
+							"time_zone": "America/Sao_Paulo",
+							"from": "2022-07-01",
+							"to": "2022-07-31",
+							"slot_minutes": 30,
+							"days": [{
+								"weekday": "monday",
+								"open": "09:00",
+								"close": "18:00",
+								"breaks": [{"start": "12:00", "end": "13:00"}]
+							}]
+						}`,
+					),
+				),
+			},
+			want: model.SlotTemplate{
+				SalonID:     1,
+				TimeZone:    "America/Sao_Paulo",
+				From:        "2022-07-01",
+				To:          "2022-07-31",
+				SlotMinutes: 30,
+				Days: []model.OpeningHours{{
+					Weekday: "monday",
+					Open:    "09:00",
+					Close:   "18:00",
+					Breaks:  []model.Break{{Start: "12:00", End: "13:00"}},
+				}},
+			},
+		},
+		{
+			name: "fail, invalid json",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/generate",
+					strings.NewReader(`{"salon_id": 1`),
+				),
+			},
+			err: apErr.ErrInvalidBody,
+		},
+		{
+			name: "fail, invalid weekday",
+			args: args{
+				ctx: context.Background(),
+				r: httptest.NewRequest(
+					"POST",
+					"/generate",
+					strings.NewReader(`{
+						"salon_id": 1,
+						"time_zone": "UTC",
+						"from": "2022-07-01",
+						"to": "2022-07-31",
+						"slot_minutes": 30,
+						"days": [{"weekday": "someday", "open": "09:00", "close": "18:00"}]
+					}`),
+				),
+			},
+			err: apErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeGenerateApp(tt.args.ctx, tt.args.r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_idempotencyKey(t *testing.T) {
+	r := httptest.NewRequest(stdHTTP.MethodPost, "/", nil)
+	_, ok := appointments.IdempotencyKey(idempotencyKey(context.Background(), r))
+	assert.False(t, ok)
+
+	r.Header.Set(HeaderIdempotencyKey, "key")
+	key, ok := appointments.IdempotencyKey(idempotencyKey(context.Background(), r))
+	assert.True(t, ok)
+	assert.Equal(t, "key", key)
+}
+
+func Test_ifMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		version int64
+		err     error
+	}{
+		{
+			name:    "success, quoted version",
+			tag:     `"7"`,
+			version: 7,
+		},
+		{
+			name: "fail, missing",
+			err:  apErr.ErrPreconditionRequired,
+		},
+		{
+			name: "fail, unquoted",
+			tag:  "7",
+			err:  apErr.ErrVersionMismatch,
+		},
+		{
+			name: "fail, not a version",
+			tag:  `"abc"`,
+			err:  apErr.ErrVersionMismatch,
+		},
+		{
+			name:    "success, any version",
+			tag:     "*",
+			version: model.AnyVersion,
+		},
+		{
+			name:    "success, weak tags are skipped",
+			tag:     `W/"6", "7"`,
+			version: 7,
+		},
+		{
+			name:    "success, list of the same version",
+			tag:     `"7" , "7"`,
+			version: 7,
+		},
+		{
+			name: "fail, weak tag",
+			tag:  `W/"7"`,
+			err:  apErr.ErrVersionMismatch,
+		},
+		{
+			name: "fail, different versions",
+			tag:  `"6", "7"`,
+			err:  apErr.ErrVersionMismatch,
+		},
+		{
+			name: "fail, negative version",
+			tag:  `"-1"`,
+			err:  apErr.ErrVersionMismatch,
+		},
+		{
+			name: "fail, any version in a list",
+			tag:  `*, "7"`,
+			err:  apErr.ErrVersionMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(stdHTTP.MethodPut, "/", nil)
+			if tt.tag != "" {
+				r.Header.Set("If-Match", tt.tag)
+			}
+			version, err := ifMatch(r)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.version, version)
+		})
+	}
+
+	r := httptest.NewRequest(stdHTTP.MethodPut, "/", nil)
+	r.Header.Add("If-Match", `W/"6"`)
+	r.Header.Add("If-Match", `"7"`)
+	version, err := ifMatch(r)
+	assert.NoError(t, err, "tags can be split across header lines")
+	assert.Equal(t, int64(7), version)
+}
+
+func Test_encodeAppResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := codeHTTP{200}.encodeAppResponse(context.Background(), w, &model.AppResponse{ID: "628ed8e442c5ab8d69b6d4fa", Version: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	err = codeHTTP{200}.encodeAppResponse(context.Background(), w, json.RawMessage(`{"id":"628ed8e442c5ab8d69b6d4fa","version":5}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"), "replayed responses keep their ETag")
+
+	w = httptest.NewRecorder()
+	err = codeHTTP{200}.encodeAppResponse(context.Background(), w, json.RawMessage(`null`))
+	assert.NoError(t, err)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	err = codeHTTP{200}.encodeAppResponse(context.Background(), w, model.AppPageResponse{})
+	assert.NoError(t, err)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestNewHTTPHandler_IdempotentReplay(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := service.NewMockAppointmentServiceI(ctrl)
+	svc.EXPECT().ChangeStatus(gomock.Any(), model.ChangeStatus{ID: "628ed8e442c5ab8d69b6d4fa", Status: model.StatusConfirmed}).
+		Return(&model.AppResponse{ID: "628ed8e442c5ab8d69b6d4fa", Status: model.StatusConfirmed, Version: 3}, nil)
+	handler := NewHTTPHandler(svc, repository.NewInMemoryIdempotency())
+
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(stdHTTP.MethodPost, "/628ed8e442c5ab8d69b6d4fa/confirm", nil)
+		r.Header.Set(HeaderIdempotencyKey, "key")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, stdHTTP.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		bodies = append(bodies, w.Body.String())
+	}
+	assert.Equal(t, bodies[0], bodies[1], "the request sent again gets the first response")
+}
+
+func TestNewHTTPHandler_IdempotencyKeyReused(t *testing.T) {
+	var ctrl = gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := service.NewMockAppointmentServiceI(ctrl)
+	svc.EXPECT().DeleteApp(gomock.Any(), model.DeleteAppointment{ID: "628ed8e442c5ab8d69b6d4fa", Version: 1}).Return(nil)
+	handler := NewHTTPHandler(svc, repository.NewInMemoryIdempotency())
+
+	var codes []int
+	for _, id := range []string{"628ed8e442c5ab8d69b6d4fa", "628ed8e442c5ab8d69b6d4fb"} {
+		r := httptest.NewRequest(stdHTTP.MethodDelete, "/"+id, nil)
+		r.Header.Set(HeaderIdempotencyKey, "key")
+		r.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{stdHTTP.StatusNoContent, stdHTTP.StatusUnprocessableEntity}, codes,
+		"a key reused on another appointment is not answered with the first response")
+}
+
+func Test_decodePatchApp(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		ifMatch string
+		want    func(t *testing.T, got interface{})
+		err     error
+	}{
+		{
+			name:    "success, reschedule",
+			body:    `{"appointment_date": "2030-06-24T10:00:00Z"}`,
+			ifMatch: `"2"`,
+			want: func(t *testing.T, got interface{}) {
+				patch := got.(model.PatchAppointment)
+				assert.Equal(t, "628ed8e442c5ab8d69b6d4fa", patch.ID)
+				assert.Equal(t, int64(2), patch.Version)
+				assert.JSONEq(t, `"2030-06-24T10:00:00Z"`, string(patch.Fields["appointment_date"]))
+			},
+		},
+		{
+			name:    "success, clear the professional",
+			body:    `{"professional_id": null}`,
+			ifMatch: `"2"`,
+			want: func(t *testing.T, got interface{}) {
+				assert.True(t, got.(model.PatchAppointment).Clears("professional_id"))
+			},
+		},
+		{
+			name: "fail, without If-Match",
+			body: `{"salon_id": 2}`,
+			err:  apErr.ErrPreconditionRequired,
+		},
+		{
+			name:    "fail, empty patch",
+			body:    `{}`,
+			ifMatch: `"2"`,
+			err:     apErr.ErrInvalidBody,
+		},
+		{
+			name:    "fail, unknown field",
+			body:    `{"status": "confirmed"}`,
+			ifMatch: `"2"`,
+			err:     apErr.ErrInvalidBody,
+		},
+		{
+			name:    "fail, user_id cannot be patched",
+			body:    `{"user_id": 2}`,
+			ifMatch: `"2"`,
+			err:     apErr.ErrInvalidBody,
+		},
+		{
+			name:    "fail, cannot clear the salon",
+			body:    `{"salon_id": null}`,
+			ifMatch: `"2"`,
+			err:     apErr.ErrInvalidBody,
+		},
+		{
+			name:    "fail, invalid service type",
+			body:    `{"service_type": "massage"}`,
+			ifMatch: `"2"`,
+			err:     apErr.ErrInvalidBody,
+		},
+		{
+			name:    "fail, wrong type",
+			body:    `{"duration_minutes": "long"}`,
+			ifMatch: `"2"`,
+			err:     apErr.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(stdHTTP.MethodPatch, "/628ed8e442c5ab8d69b6d4fa", strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", "628ed8e442c5ab8d69b6d4fa")
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+
+			got, err := decodePatchApp(context.Background(), r)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.want != nil {
+				tt.want(t, got)
+			}
+		})
+	}
+}
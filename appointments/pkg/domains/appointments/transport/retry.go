@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	"github.com/pkg/errors"
+	delivery "github.com/streadway/amqp"
+)
+
+const (
+	headerRetryCount  = "x-retry-count"
+	headerErrorReason = "x-error-reason"
+	headerErrorCode   = "x-error-code"
+	headerQueue       = "x-original-queue"
+)
+
+// RetryPolicy says how failed deliveries are retried. A message failing with
+// a transient error waits Delays[n] in the delay queue of its n-th retry and
+// is then routed back to its queue. Once every delay is used, or right away
+// for poison messages, it is published to DeadLetterExchange.
+type RetryPolicy struct {
+	Delays             []time.Duration
+	DeadLetterExchange string
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Delays:             []time.Duration{time.Second, 10 * time.Second, time.Minute},
+	DeadLetterExchange: "appointments.dlx",
+}
+
+// DeclareRetryTopology declares, for every queue, its delay queues and the
+// dead-letter queue bound to the dead-letter exchange of the policy.
+func DeclareRetryTopology(ch Declarer, policy RetryPolicy, queues ...string) error {
+	if err := ch.ExchangeDeclare(policy.DeadLetterExchange, delivery.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return errors.Wrap(err, "declare dead-letter exchange")
+	}
+
+	for _, queue := range queues {
+		for attempt, delay := range policy.Delays {
+			args := delivery.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			}
+			if _, err := ch.QueueDeclare(retryQueue(queue, attempt+1), true, false, false, false, args); err != nil {
+				return errors.Wrapf(err, "declare delay queue of %s", queue)
+			}
+		}
+
+		dead := queue + ".dead"
+		if _, err := ch.QueueDeclare(dead, true, false, false, false, nil); err != nil {
+			return errors.Wrapf(err, "declare dead-letter queue of %s", queue)
+		}
+
+		if err := ch.QueueBind(dead, queue, policy.DeadLetterExchange, false, nil); err != nil {
+			return errors.Wrapf(err, "bind dead-letter queue of %s", queue)
+		}
+	}
+
+	return nil
+}
+
+// retryQueue is the delay queue a message waits in before its n-th retry.
+func retryQueue(queue string, n int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, n)
+}
+
+// transient reports whether err may go away by itself, so the message is
+// worth retrying. Anything else, like an invalid body, fails the same way
+// every time. A redelivery racing the first delivery of the same key is
+// retried too, by then it is answered with the stored response.
+func transient(err error) bool {
+	return errors.Is(err, appErr.ErrDatabase) || errors.Is(err, appErr.ErrMemoryDatabase) ||
+		errors.Is(err, appErr.ErrRequestInProgress)
+}
+
+// poison reports whether err means the message itself is broken, like an
+// invalid body or an error nobody expected, so it is dead-lettered for
+// someone to look at. Business outcomes, like a slot already booked, are
+// only replied to.
+func poison(err error) bool {
+	return errors.Is(err, appErr.ErrInvalidBody) || errors.Is(err, appErr.ErrTypeAssertion) ||
+		!appErr.RESTErrorBussines.Maps(err)
+}
+
+// retryCount returns how many times the delivery has been retried already.
+func retryCount(deliv *delivery.Delivery) int {
+	switch count := deliv.Headers[headerRetryCount].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	case string:
+		n, _ := strconv.Atoi(count)
+		return n
+	}
+
+	return 0
+}
+
+// republish copies the delivery into a new message with extra headers, so it
+// keeps its body, reply address and correlation on the way to another queue.
+func republish(deliv *delivery.Delivery, headers delivery.Table) delivery.Publishing {
+	table := delivery.Table{}
+	for k, v := range deliv.Headers {
+		table[k] = v
+	}
+	for k, v := range headers {
+		table[k] = v
+	}
+
+	return delivery.Publishing{
+		Headers:         table,
+		ContentType:     deliv.ContentType,
+		ContentEncoding: deliv.ContentEncoding,
+		DeliveryMode:    delivery.Persistent,
+		CorrelationId:   deliv.CorrelationId,
+		ReplyTo:         deliv.ReplyTo,
+		MessageId:       deliv.MessageId,
+		Timestamp:       deliv.Timestamp,
+		Type:            deliv.Type,
+		AppId:           deliv.AppId,
+		Body:            deliv.Body,
+	}
+}
@@ -0,0 +1,128 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	appErr "github.com/LeandroAlcantara-1997/appointment/pkg/domains/appointments/error"
+	pkgErrors "github.com/pkg/errors"
+	delivery "github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDeclarer records the declared broker objects.
+type fakeDeclarer struct {
+	exchanges []string
+	queues    map[string]delivery.Table
+	bindings  map[string]string
+	err       error
+}
+
+func (d *fakeDeclarer) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args delivery.Table) error {
+	d.exchanges = append(d.exchanges, name)
+	return d.err
+}
+
+func (d *fakeDeclarer) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args delivery.Table) (delivery.Queue, error) {
+	if d.queues == nil {
+		d.queues = make(map[string]delivery.Table)
+	}
+	d.queues[name] = args
+	return delivery.Queue{Name: name}, nil
+}
+
+func (d *fakeDeclarer) QueueBind(name, key, exchange string, noWait bool, args delivery.Table) error {
+	if d.bindings == nil {
+		d.bindings = make(map[string]string)
+	}
+	d.bindings[name] = exchange + "/" + key
+	return nil
+}
+
+func TestDeclareRetryTopology(t *testing.T) {
+	policy := RetryPolicy{Delays: []time.Duration{time.Second, time.Minute}, DeadLetterExchange: "dlx"}
+	tests := []struct {
+		name     string
+		ch       *fakeDeclarer
+		queues   map[string]delivery.Table
+		bindings map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "success, declared delay and dead-letter queues",
+			ch:   &fakeDeclarer{},
+			queues: map[string]delivery.Table{
+				"make-appointment.retry.1": {
+					"x-message-ttl":             int64(1000),
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": MakeQueue,
+				},
+				"make-appointment.retry.2": {
+					"x-message-ttl":             int64(60000),
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": MakeQueue,
+				},
+				"make-appointment.dead": nil,
+			},
+			bindings: map[string]string{"make-appointment.dead": "dlx/" + MakeQueue},
+		},
+		{
+			name:    "fail, cannot declare the dead-letter exchange",
+			ch:      &fakeDeclarer{err: errors.New("channel closed")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DeclareRetryTopology(tt.ch, policy, MakeQueue)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, []string{"dlx"}, tt.ch.exchanges)
+			assert.Equal(t, tt.queues, tt.ch.queues)
+			assert.Equal(t, tt.bindings, tt.ch.bindings)
+		})
+	}
+}
+
+func Test_transient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "database", err: pkgErrors.Wrap(appErr.ErrDatabase, "connection refused"), want: true},
+		{name: "memory database", err: appErr.ErrMemoryDatabase, want: true},
+		{name: "invalid body", err: pkgErrors.Wrap(appErr.ErrInvalidBody, "salon_id required")},
+		{name: "not found", err: appErr.ErrNotFound},
+		{name: "not owner", err: appErr.ErrNotOwner},
+		{name: "invalid id", err: pkgErrors.Wrap(appErr.ErrInvalidID, "the provided hex string is not a valid ObjectID")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, transient(tt.err))
+		})
+	}
+}
+
+func Test_poison(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "invalid body", err: pkgErrors.Wrap(appErr.ErrInvalidBody, "salon_id required"), want: true},
+		{name: "type assertion", err: appErr.ErrTypeAssertion, want: true},
+		{name: "unclassified", err: pkgErrors.New("unexpected end of JSON input"), want: true},
+		{name: "database", err: pkgErrors.Wrap(appErr.ErrDatabase, "connection refused")},
+		{name: "not found", err: appErr.ErrNotFound},
+		{name: "already booked", err: appErr.ErrAlreadyBooked},
+		{name: "overlapping", err: appErr.ErrOverlappingAppointment},
+		{name: "version mismatch", err: appErr.ErrVersionMismatch},
+		{name: "invalid transition", err: pkgErrors.Wrap(appErr.ErrInvalidTransition, "completed -> cancelled")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, poison(tt.err))
+		})
+	}
+}
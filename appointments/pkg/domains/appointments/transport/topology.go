@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	delivery "github.com/streadway/amqp"
+)
+
+// Topology names the broker objects the appointment consumers rely on.
+// Requests are published to Exchange with the queue name as routing key.
+type Topology struct {
+	Exchange                string
+	CreateQueue             string
+	MakeQueue               string
+	GenerateQueue           string
+	UpdateQueue             string
+	CancelQueue             string
+	DeleteQueue             string
+	FindQueue               string
+	FindAllQueue            string
+	FindByUserQueue         string
+	FindBySalonQueue        string
+	FindByProfessionalQueue string
+	AvailableQueue          string
+	ConsumerTag             string
+	Retry                   RetryPolicy
+}
+
+var DefaultTopology = Topology{
+	Exchange:                "appointments",
+	CreateQueue:             CreateQueue,
+	MakeQueue:               MakeQueue,
+	GenerateQueue:           GenerateQueue,
+	UpdateQueue:             UpdateQueue,
+	CancelQueue:             CancelQueue,
+	DeleteQueue:             DeleteQueue,
+	FindQueue:               FindQueue,
+	FindAllQueue:            FindAllQueue,
+	FindByUserQueue:         FindByUserQueue,
+	FindBySalonQueue:        FindBySalonQueue,
+	FindByProfessionalQueue: FindByProfessionalQueue,
+	AvailableQueue:          AvailableQueue,
+	Retry:                   DefaultRetryPolicy,
+}
+
+// Queues returns the queues consumed by NewBroker.
+func (t Topology) Queues() []string {
+	return []string{
+		t.CreateQueue,
+		t.MakeQueue,
+		t.GenerateQueue,
+		t.UpdateQueue,
+		t.CancelQueue,
+		t.DeleteQueue,
+		t.FindQueue,
+		t.FindAllQueue,
+		t.FindByUserQueue,
+		t.FindBySalonQueue,
+		t.FindByProfessionalQueue,
+		t.AvailableQueue,
+	}
+}
+
+// consumerTag identifies the consumer of queue so it can be cancelled, tags
+// must be unique on a channel.
+func (t Topology) consumerTag(queue string) string {
+	if t.ConsumerTag == "" {
+		return queue
+	}
+
+	return fmt.Sprintf("%s.%s", t.ConsumerTag, queue)
+}
+
+// Declarer declares the broker objects of the topology.
+// It is implemented by *amqp.Channel.
+type Declarer interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args delivery.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args delivery.Table) (delivery.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args delivery.Table) error
+}
+
+// DeclareTopology declares the exchange, every queue bound to it and the
+// retry and dead-letter queues. Declaring is idempotent as long as the
+// existing objects have the same arguments.
+func DeclareTopology(ch Declarer, t Topology) error {
+	if err := ch.ExchangeDeclare(t.Exchange, delivery.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return errors.Wrapf(err, "declare exchange %s", t.Exchange)
+	}
+
+	for _, queue := range t.Queues() {
+		if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			return errors.Wrapf(err, "declare queue %s", queue)
+		}
+
+		if err := ch.QueueBind(queue, queue, t.Exchange, false, nil); err != nil {
+			return errors.Wrapf(err, "bind queue %s", queue)
+		}
+	}
+
+	return DeclareRetryTopology(ch, t.Retry, t.Queues()...)
+}
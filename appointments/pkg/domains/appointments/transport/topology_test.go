@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeclareTopology(t *testing.T) {
+	topology := DefaultTopology
+	topology.Exchange = "salons"
+	topology.Retry = RetryPolicy{Delays: []time.Duration{time.Second}, DeadLetterExchange: "salons.dlx"}
+	bindings := make(map[string]string)
+	for _, queue := range topology.Queues() {
+		bindings[queue] = "salons/" + queue
+		bindings[queue+".dead"] = "salons.dlx/" + queue
+	}
+	tests := []struct {
+		name      string
+		ch        *fakeDeclarer
+		exchanges []string
+		bindings  map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "success, declared and bound every queue",
+			ch:        &fakeDeclarer{},
+			exchanges: []string{"salons", "salons.dlx"},
+			bindings:  bindings,
+		},
+		{
+			name:      "fail, cannot declare the exchange",
+			ch:        &fakeDeclarer{err: errors.New("channel closed")},
+			exchanges: []string{"salons"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DeclareTopology(tt.ch, topology)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.exchanges, tt.ch.exchanges)
+			assert.Equal(t, tt.bindings, tt.ch.bindings)
+			if !tt.wantErr {
+				// every queue, its delay queue and its dead-letter queue
+				assert.Len(t, tt.ch.queues, 3*len(topology.Queues()))
+			}
+		})
+	}
+}
+
+func TestTopology_consumerTag(t *testing.T) {
+	assert.Equal(t, "appointments.make", Topology{ConsumerTag: "appointments"}.consumerTag("make"))
+	assert.Equal(t, "make", Topology{}.consumerTag("make"))
+}